@@ -1,6 +1,6 @@
 module github.com/aporia-ai/kubesurvival/v2
 
-go 1.15
+go 1.16
 
 require (
 	github.com/containerd/containerd v1.2.5 // indirect
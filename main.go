@@ -1,45 +1,80 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"io/ioutil"
-	"math"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 
-	"github.com/aporia-ai/kubesurvival/v2/pkg/kubesimulator"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/manifests"
 	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/optimizer"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/output"
 	"github.com/aporia-ai/kubesurvival/v2/pkg/parser"
 	"github.com/aporia-ai/kubesurvival/v2/pkg/podgen"
 	"gopkg.in/yaml.v2"
-	v1 "k8s.io/api/core/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 type Config struct {
-	Nodes struct {
-		AWS struct {
-			Region        string   `yaml:"region"`
-			InstanceTypes []string `yaml:"instanceTypes"`
-		} `yaml:"aws"`
-	} `yaml:"nodes"`
-	Pods string `yaml:"pods"`
-}
-
-type Result struct {
-	InstanceType       string
-	NodeCount          int
-	TotalPricePerMonth float64
+	// Nodes are the sections of node sources by name, e.g aws, in the order of the config file.
+	Nodes  yaml.MapSlice `yaml:"nodes"`
+	Search struct {
+		Mode             string `yaml:"mode"`
+		MaxNodeGroups    int    `yaml:"maxNodeGroups"`
+		MaxNodesPerGroup int    `yaml:"maxNodesPerGroup"`
+	} `yaml:"search"`
+	Simulation struct {
+		MaxTicks int `yaml:"maxTicks"`
+	} `yaml:"simulation"`
+	Pods      string   `yaml:"pods"`
+	Manifests []string `yaml:"manifests"`
+	Snapshot  string   `yaml:"snapshot"`
 }
 
 func main() {
-	// Read argument
-	if len(os.Args) != 2 {
-		fmt.Println("USAGE: ./kubesurvival <YAML_CONFIG_PATH>")
+	// Read arguments
+	explain := flag.Bool("explain", false, "explain why cheaper clusters were rejected")
+	report := flag.Bool("report", false, "print the pods and utilization of every node of the cheapest cluster")
+	outputFormat := flag.String("output", "table", "output format: table, json, yaml or csv")
+	top := flag.Int("top", 1, "number of cheapest clusters to rank")
+	flag.Usage = func() {
+		fmt.Println("USAGE: ./kubesurvival [--explain] [--report] [--output FORMAT] [--top N] <YAML_CONFIG_PATH>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	switch *outputFormat {
+	case "table", "json", "yaml", "csv":
+	default:
+		fmt.Printf("[!] Unknown output format: %s\n", *outputFormat)
+		os.Exit(1)
+	}
+
+	// Rejections and node reports are only printed as tables
+	if *outputFormat != "table" && (*explain || *report) {
+		fmt.Printf("[!] --explain and --report can only be used with --output table\n")
+		os.Exit(1)
+	}
+
+	if *top < 1 {
+		fmt.Printf("[!] --top must be at least 1\n")
 		os.Exit(1)
 	}
 
 	// Read config file
-	configFile, err := ioutil.ReadFile(os.Args[1])
+	configFile, err := ioutil.ReadFile(flag.Arg(0))
 	if err != nil {
 		fmt.Printf("[!] Could not read config file: %s\n", err)
 		return
@@ -54,135 +89,415 @@ func main() {
 	}
 
 	// Parse & generate pods
-	exp, parseErrors := parser.Parse(config.Pods)
-	if len(parseErrors) > 0 {
-		for _, parseError := range parseErrors {
-			fmt.Printf("[!] Parse error: %s\n", parseError.Error())
+	pods := []*corev1.Pod{}
+	if config.Pods != "" {
+		program, parseErrors := parser.Parse(config.Pods)
+		if len(parseErrors) > 0 {
+			for _, parseError := range parseErrors {
+				fmt.Printf("[!] Parse error: %s\n", parseError.Error())
+			}
+			return
 		}
-		return
+
+		podgenPods, podgenErrors := podgen.Podgen(program)
+		if len(podgenErrors) > 0 {
+			for _, podgenError := range podgenErrors {
+				fmt.Printf("[!] PodGen error: %s\n", podgenError.Error())
+			}
+			return
+		}
+
+		pods = append(pods, podgenPods...)
 	}
 
-	pods, podgenErrors := podgen.Podgen(exp)
-	if len(podgenErrors) > 0 {
-		for _, podgenError := range podgenErrors {
-			fmt.Printf("[!] PodGen error: %s\n", podgenError.Error())
+	// Load pods from Kubernetes manifests
+	if len(config.Manifests) > 0 {
+		paths := []string{}
+		for _, path := range config.Manifests {
+			paths = append(paths, configPath(path))
+		}
+
+		manifestPods, err := manifests.Load(paths...)
+		if err != nil {
+			fmt.Printf("[!] Could not load manifests: %s\n", err)
+			return
 		}
+
+		pods = append(pods, manifestPods...)
+	}
+
+	// Load pods from a cluster snapshot, which is also used as a baseline
+	var snapshot *manifests.Snapshot
+	if config.Snapshot != "" {
+		snapshot, err = manifests.LoadSnapshot(configPath(config.Snapshot))
+		if err != nil {
+			fmt.Printf("[!] Could not load snapshot: %s\n", err)
+			return
+		}
+
+		pods = append(pods, snapshot.Pods...)
+	}
+
+	if len(pods) == 0 {
+		fmt.Printf("[!] No pods to simulate, please set pods or manifests in the config file.\n")
 		return
 	}
 
 	// Generate nodes
-	ns := &nodesource.AWSNodeSource{
-		AWSRegion:     config.Nodes.AWS.Region,
-		InstanceTypes: config.Nodes.AWS.InstanceTypes,
+	var snapshotInstanceTypes []string
+	var snapshotNodeCounts map[string]int
+	if snapshot != nil {
+		snapshotInstanceTypes, snapshotNodeCounts, err = snapshot.InstanceTypes()
+		if err != nil {
+			fmt.Printf("[!] Could not read snapshot nodes: %s\n", err)
+			return
+		}
+	}
+
+	sections := config.Nodes
+	if len(sections) == 0 {
+		sections = yaml.MapSlice{{Key: "aws"}}
+	}
+
+	nodeSources := []nodesource.NodeSource{}
+	for i, section := range sections {
+		options := nodesource.Options{Path: configPath}
+
+		// By default, the first node source searches for a cheaper cluster made of the
+		// instance types that are used today
+		if i == 0 && snapshot != nil {
+			options.Region = snapshot.Region()
+			options.InstanceTypes = snapshotInstanceTypes
+		}
+
+		ns, err := nodesource.New(fmt.Sprint(section.Key), section.Value, options)
+		if err != nil {
+			fmt.Printf("[!] %s\n", err)
+			return
+		}
+
+		nodeSources = append(nodeSources, ns)
+	}
+
+	nodeTypes := []nodesource.Node{}
+	for _, ns := range nodeSources {
+		sourceNodeTypes, err := ns.GetNodes()
+		if err != nil {
+			fmt.Printf("Could not get node types: %s\n", err)
+			return
+		}
+
+		nodeTypes = append(nodeTypes, sourceNodeTypes...)
+	}
+
+	// The region of the report is the one of the first node source with a region
+	region := ""
+	for _, nodeType := range nodeTypes {
+		if region = nodeType.GetLabels()["topology.kubernetes.io/region"]; region != "" {
+			break
+		}
+	}
+
+	// Calculate the price of the cluster in the snapshot, by the first node source
+	var baseline *optimizer.Result
+	if snapshot != nil {
+		baselineSource, ok := nodeSources[0].(nodesource.BaselineNodeSource)
+		if !ok {
+			fmt.Printf("[!] nodes.%s can't price the nodes of the snapshot\n", sections[0].Key)
+			return
+		}
+
+		baselineNodeTypes, err := baselineSource.GetBaselineNodes(snapshotInstanceTypes)
+		if err != nil {
+			fmt.Printf("[!] Could not price the nodes of the snapshot: %s\n", err)
+			return
+		}
+
+		baseline = &optimizer.Result{}
+		for i, instanceType := range snapshotInstanceTypes {
+			baseline.NodeGroups = append(baseline.NodeGroups, optimizer.NodeGroup{
+				NodeType:  baselineNodeTypes[i],
+				NodeCount: snapshotNodeCounts[instanceType],
+			})
+		}
+		baseline.TotalPricePerMonth = optimizer.PricePerMonth(baseline.NodeGroups...)
+	}
+
+	// Find the cheapest cluster
+	opt := &optimizer.Optimizer{
+		Pods:             pods,
+		NodeTypes:        nodeTypes,
+		MaxNodeGroups:    config.Search.MaxNodeGroups,
+		MaxNodesPerGroup: config.Search.MaxNodesPerGroup,
+		MaxTicks:         config.Simulation.MaxTicks,
+		Top:              *top,
+	}
+
+	var result *optimizer.Result
+	switch config.Search.Mode {
+	case "", "single":
+		result, err = opt.FindCheapest()
+	case "mixed":
+		result, err = opt.FindCheapestMix()
+	default:
+		fmt.Printf("[!] Unknown search mode: %s\n", config.Search.Mode)
+		return
 	}
 
-	nodeTypes, err := ns.GetNodes()
 	if err != nil {
-		fmt.Printf("Could not get node types: %s\n", err)
+		fmt.Printf("[!] %s\n", err)
 		return
 	}
 
-	// Remove node types if there's a pod with more resources than it
-	filteredNodeTypes := filterNodeTypes(nodeTypes, pods)
-	if len(filteredNodeTypes) == 0 {
-		fmt.Printf("[!] No nodes are available for simulation.\n")
+	if *outputFormat != "table" {
+		err = output.Write(os.Stdout, *outputFormat, output.NewReport(region, opt.Results, baseline, opt.Candidates))
+		if err != nil {
+			fmt.Printf("[!] %s\n", err)
+		}
 		return
 	}
 
-	// Main loop
-	var result *Result
-	for _, nodeType := range filteredNodeTypes {
-		// We never want a cluster with only 1 node
-		nodeCount := 2
+	if *explain {
+		printRejections(opt.Rejections)
+	}
 
-		for {
-			// Calculate total price per month
-			totalPricePerMonth := float64(nodeCount) * nodeType.GetHourlyPrice() * 24 * 31
+	if result == nil {
+		fmt.Printf("[!] Could not converge to a solution.\n")
+		return
+	}
 
-			// Do we even need to simulate?
-			if result != nil && totalPricePerMonth > result.TotalPricePerMonth {
-				break
-			}
+	if baseline != nil {
+		fmt.Printf("Current cluster:\n")
+		printNodeGroups(baseline)
+		fmt.Printf("Current Price per Month: USD $%.2f\n", baseline.TotalPricePerMonth)
+		printPricingModels(baseline)
+		fmt.Printf("\n")
+		fmt.Printf("Cheapest cluster:\n")
+	}
 
-			// Generate a list of nodes from this type
-			nodes := []nodesource.Node{}
-			for i := 0; i < nodeCount; i++ {
-				nodes = append(nodes, nodeType)
-			}
+	printNodeGroups(result)
+	fmt.Printf("Total Price per Month: USD $%.2f\n", result.TotalPricePerMonth)
+	printPricingModels(result)
+
+	if *report {
+		fmt.Printf("\n")
+		printReport(result)
+	}
 
-			// Simulate cluster
-			simulator := &kubesimulator.KubernetesSimulator{}
-			isSimulationSuccessful, err := simulator.Simulate(pods, nodes)
-			if err != nil {
-				fmt.Printf("[!] Failed to simulate a Kubernetes cluster: %s\n", err)
-				return
+	if baseline != nil {
+		savings := baseline.TotalPricePerMonth - result.TotalPricePerMonth
+		percentage := 0.0
+		if baseline.TotalPricePerMonth > 0 {
+			percentage = savings / baseline.TotalPricePerMonth * 100
+		}
+
+		fmt.Printf("Savings per Month: USD $%.2f (%.1f%%)\n", savings, percentage)
+	}
+
+	if *top > 1 {
+		fmt.Printf("\n")
+		printRanking(opt.Results)
+	}
+}
+
+// printRanking prints the cheapest clusters, and how much more expensive they are than the cheapest one.
+func printRanking(results []*optimizer.Result) {
+	fmt.Printf("Top %d clusters:\n", len(results))
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "  #\tNode groups\tNodes\tPrice per Month\tDifference\n")
+
+	for i, result := range results {
+		groups := []string{}
+		nodeCount := 0
+		for _, group := range result.NodeGroups {
+			groups = append(groups, fmt.Sprintf("%s x %d", group.NodeType.GetName(), group.NodeCount))
+			nodeCount += group.NodeCount
+		}
+
+		difference := "-"
+		if i > 0 {
+			cheapest := results[0].TotalPricePerMonth
+			difference = fmt.Sprintf("+USD $%.2f", result.TotalPricePerMonth-cheapest)
+			if cheapest > 0 {
+				difference += fmt.Sprintf(" (%.1f%%)", (result.TotalPricePerMonth-cheapest)/cheapest*100)
 			}
+		}
 
-			if isSimulationSuccessful {
-				result = &Result{
-					InstanceType:       nodeType.InstanceType,
-					NodeCount:          nodeCount,
-					TotalPricePerMonth: totalPricePerMonth,
-				}
+		fmt.Fprintf(writer, "  %d\t%s\t%d\tUSD $%.2f\t%s\n", i+1, strings.Join(groups, " + "), nodeCount,
+			result.TotalPricePerMonth, difference)
+	}
 
-				break
+	writer.Flush()
+}
+
+// printNodeGroups prints the node groups of a result.
+func printNodeGroups(result *optimizer.Result) {
+	if len(result.NodeGroups) == 1 {
+		fmt.Printf("Instance type: %s\n", result.NodeGroups[0].NodeType.GetName())
+		fmt.Printf("Node count: %s\n", formatNodeCount(result.NodeGroups[0]))
+	} else {
+		fmt.Printf("Node groups:\n")
+		for _, group := range result.NodeGroups {
+			fmt.Printf("  - Instance type: %s, Node count: %s\n", group.NodeType.GetName(), formatNodeCount(group))
+		}
+	}
+}
+
+// formatNodeCount formats the node count of a node group, and how many of its nodes are reserved.
+func formatNodeCount(group optimizer.NodeGroup) string {
+	if group.ReservedNodes() == 0 {
+		return strconv.Itoa(group.NodeCount)
+	}
+
+	return fmt.Sprintf("%d (%d reserved)", group.NodeCount, group.ReservedNodes())
+}
+
+// printPricingModels prints the price per month of the volumes of a cluster, and its price if all
+// of its nodes ran on-demand or on spot. Nothing is printed for unknown spot prices or no volumes.
+func printPricingModels(result *optimizer.Result) {
+	if storagePricePerHour := optimizer.StoragePricePerHour(result.NodeGroups...); storagePricePerHour > 0 {
+		fmt.Printf("  Storage: USD $%.2f\n", storagePricePerHour*optimizer.HoursPerMonth)
+	}
+
+	spotPricePerHour, ok := optimizer.SpotPricePerHour(result.NodeGroups...)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("  On-demand: USD $%.2f, Spot: USD $%.2f\n",
+		optimizer.OnDemandPricePerHour(result.NodeGroups...)*optimizer.HoursPerMonth,
+		spotPricePerHour*optimizer.HoursPerMonth)
+}
+
+// reportedResources are the resources in the utilization report, in order.
+var reportedResources = []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory, "nvidia.com/gpu", corev1.ResourcePods}
+
+// printReport prints the pods and the utilization of every node of a simulated cluster.
+func printReport(result *optimizer.Result) {
+	fmt.Printf("Nodes:\n")
+	for _, node := range result.Nodes {
+		fmt.Printf("  %s (%s, %s)\n", node.Name, node.InstanceType, node.Zone)
+
+		usage := []string{}
+		for _, name := range reportedResources {
+			allocatable := node.Allocatable[name]
+			if allocatable.IsZero() {
+				continue
 			}
 
-			// Simple heuristic as an alternative to nodeCount++ to make convergence faster.
-			nodeCount += int(math.Max(float64(nodeCount)/15, 1))
+			allocated := node.Allocated[name]
+			usage = append(usage, fmt.Sprintf("%s: %s/%s (%.0f%%)", name, formatQuantity(name, allocated),
+				formatQuantity(name, allocatable), float64(allocated.MilliValue())/float64(allocatable.MilliValue())*100))
+		}
+		fmt.Printf("    %s\n", strings.Join(usage, ", "))
+
+		for _, pod := range node.Pods {
+			fmt.Printf("    - %s\n", pod)
 		}
 	}
 
-	if result != nil {
-		fmt.Printf("Instance type: %s\n", result.InstanceType)
-		fmt.Printf("Node count: %d\n", result.NodeCount)
-		fmt.Printf("Total Price per Month: USD $%.2f\n", result.TotalPricePerMonth)
-	} else {
-		fmt.Printf("[!] Could not converge to a solution.\n")
+	stranded := []string{}
+	for _, name := range reportedResources {
+		if quantity, ok := result.StrandedCapacity[name]; ok && !quantity.IsZero() {
+			stranded = append(stranded, fmt.Sprintf("%s: %s", name, formatQuantity(name, quantity)))
+		}
+	}
+	if len(stranded) == 0 {
+		stranded = append(stranded, "none")
 	}
+
+	fmt.Printf("Stranded capacity (free on nodes that no pod fits on): %s\n", strings.Join(stranded, ", "))
 }
 
-func filterNodeTypes(nodeTypes []*nodesource.AWSNode, pods []*v1.Pod) []*nodesource.AWSNode {
-	result := []*nodesource.AWSNode{}
-	for _, nodeType := range nodeTypes {
-		nodeHasEnoughResources := true
-
-		for _, pod := range pods {
-			// Is Pod CPU > Node CPU?
-			nodeCpu := resource.MustParse(nodeType.GetNodeConfig("node").Status.Allocatable["cpu"])
-			podCpu := pod.Spec.Containers[0].Resources.Requests.Cpu()
-			if podCpu.Cmp(nodeCpu) > 0 {
-				fmt.Printf("WARNING: Ignoring node type %s with %s CPU because there's a pod with more CPU: %s\n",
-					nodeType.InstanceType, nodeCpu.String(), podCpu.String())
-				nodeHasEnoughResources = false
+// formatQuantity formats CPU in cores, memory in GiB and other resources as integers.
+func formatQuantity(name corev1.ResourceName, quantity resource.Quantity) string {
+	switch name {
+	case corev1.ResourceCPU:
+		return strconv.FormatFloat(float64(quantity.MilliValue())/1000, 'f', -1, 64)
+	case corev1.ResourceMemory:
+		return fmt.Sprintf("%.1fGi", float64(quantity.Value())/(1<<30))
+	default:
+		return strconv.FormatInt(quantity.Value(), 10)
+	}
+}
+
+// maxExplainedPods is the number of unscheduled pods that are explained for each rejected cluster.
+const maxExplainedPods = 5
+
+// printRejections prints why the most expensive rejected cluster of each node type couldn't run all pods.
+func printRejections(rejections []*optimizer.Rejection) {
+	for _, rejection := range rejections {
+		fmt.Printf("Rejected cluster (USD $%.2f per month):\n", rejection.TotalPricePerMonth)
+		for _, group := range rejection.NodeGroups {
+			fmt.Printf("  - Instance type: %s, Node count: %d\n", group.NodeType.GetName(), group.NodeCount)
+		}
+
+		nodeCount := 0
+		for _, group := range rejection.NodeGroups {
+			nodeCount += group.NodeCount
+		}
+
+		if rejection.Diagnosis != "" {
+			fmt.Printf("  %s\n", rejection.Diagnosis)
+		}
+
+		fmt.Printf("  %d unscheduled pod(s):\n", len(rejection.UnscheduledPods))
+		for i, pod := range rejection.UnscheduledPods {
+			if i == maxExplainedPods {
+				fmt.Printf("    ... and %d more\n", len(rejection.UnscheduledPods)-maxExplainedPods)
 				break
 			}
 
-			// Is Pod Memory > Node Memory?
-			nodeMemory := resource.MustParse(nodeType.GetNodeConfig("node").Status.Allocatable["memory"])
-			podMemory := pod.Spec.Containers[0].Resources.Requests.Memory()
-			if podMemory.Cmp(nodeMemory) > 0 {
-				fmt.Printf("WARNING: Ignoring node type %s with %s memory because there's a pod with more memory: %s\n",
-					nodeType.InstanceType, nodeMemory.String(), podMemory.String())
-				nodeHasEnoughResources = false
-				break
+			fmt.Printf("    - %s/%s (%s)\n", pod.Namespace, pod.Name, formatResources(pod.Requests))
+
+			if fitting := nodeCount - len(pod.Reasons); fitting > 0 {
+				fmt.Printf("        fits on %d node(s), but is queued behind a pod that doesn't fit\n", fitting)
 			}
 
-			// Is Pod Memory > Node Memory?
-			nodeGpu := resource.MustParse(nodeType.GetNodeConfig("node").Status.Allocatable["nvidia.com/gpu"])
-			podGpu := pod.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"]
-			if podGpu.Cmp(nodeGpu) > 0 {
-				fmt.Printf("WARNING: Ignoring node type %s with %s GPU because there's a pod with more GPU: %s\n",
-					nodeType.InstanceType, nodeGpu.String(), podGpu.String())
-				nodeHasEnoughResources = false
-				break
+			// Group nodes with the same reasons, e.g "Insufficient cpu: 3 node(s)"
+			nodesByReasons := map[string]int{}
+			for _, reasons := range pod.Reasons {
+				nodesByReasons[strings.Join(reasons, ", ")]++
 			}
-		}
 
-		if nodeHasEnoughResources {
-			result = append(result, nodeType)
+			reasons := []string{}
+			for reason := range nodesByReasons {
+				reasons = append(reasons, reason)
+			}
+			sort.Strings(reasons)
+
+			for _, reason := range reasons {
+				fmt.Printf("        %s: %d node(s)\n", reason, nodesByReasons[reason])
+			}
 		}
+
+		fmt.Printf("\n")
+	}
+}
+
+// formatResources formats a resource list, e.g "cpu: 2, memory: 4Gi".
+func formatResources(resources corev1.ResourceList) string {
+	names := []string{}
+	for name := range resources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	formatted := []string{}
+	for _, name := range names {
+		quantity := resources[corev1.ResourceName(name)]
+		formatted = append(formatted, fmt.Sprintf("%s: %s", name, quantity.String()))
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// configPath resolves a path in the config file, which is relative to the config file.
+func configPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
 	}
 
-	return result
+	return filepath.Join(filepath.Dir(flag.Arg(0)), path)
 }
@@ -0,0 +1,42 @@
+package admission
+
+import (
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+)
+
+// AddExtendedResourceTolerations adds a toleration for every extended resource that a pod
+// requests, e.g nvidia.com/gpu, like the ExtendedResourceToleration admission controller.
+// Nodes with extended resources are tainted with the resource name, e.g GPU nodes on GKE, so
+// that only pods that request the resource run on them.
+func AddExtendedResourceTolerations(spec *v1.PodSpec) {
+	names := []string{}
+	for _, container := range append(append([]v1.Container{}, spec.Containers...), spec.InitContainers...) {
+		for name := range container.Resources.Requests {
+			if v1helper.IsExtendedResourceName(name) {
+				names = append(names, string(name))
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		toleration := v1.Toleration{Key: name, Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}
+		if !hasToleration(spec.Tolerations, toleration) {
+			spec.Tolerations = append(spec.Tolerations, toleration)
+		}
+	}
+}
+
+// hasToleration checks whether a toleration is in a list of tolerations.
+func hasToleration(tolerations []v1.Toleration, toleration v1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(&toleration) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+package kubesimulator
+
+import (
+	"fmt"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/scheduler"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm"
+	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// clusterState gives predicates access to the pods and nodes of the whole cluster, since the
+// generic scheduler only passes them the node they filter. It implements the node and pod
+// listers of the inter-pod affinity predicate.
+type clusterState struct {
+	nodeInfoMap map[string]*nodeinfo.NodeInfo
+}
+
+// GetNodeInfo returns a node by its name.
+func (c *clusterState) GetNodeInfo(nodeName string) (*v1.Node, error) {
+	info, ok := c.nodeInfoMap[nodeName]
+	if !ok || info.Node() == nil {
+		return nil, fmt.Errorf("no node named %s", nodeName)
+	}
+
+	return info.Node(), nil
+}
+
+// List returns the pods that are bound to nodes and match the selector.
+func (c *clusterState) List(selector labels.Selector) ([]*v1.Pod, error) {
+	return c.FilteredList(func(*v1.Pod) bool { return true }, selector)
+}
+
+// FilteredList returns the pods that are bound to nodes, pass the filter and match the selector.
+func (c *clusterState) FilteredList(filter algorithm.PodFilter, selector labels.Selector) ([]*v1.Pod, error) {
+	pods := []*v1.Pod{}
+	for nodeName, info := range c.nodeInfoMap {
+		for _, pod := range info.Pods() {
+			// Pods are added to nodes before they are bound, so they may not have a node name yet
+			if pod.Spec.NodeName != nodeName {
+				boundPod := *pod
+				boundPod.Spec.NodeName = nodeName
+				pod = &boundPod
+			}
+
+			if filter(pod) && selector.Matches(labels.Set(pod.Labels)) {
+				pods = append(pods, pod)
+			}
+		}
+	}
+
+	return pods, nil
+}
+
+// clusterStateScheduler updates the cluster state before the scheduler runs.
+type clusterStateScheduler struct {
+	scheduler.Scheduler
+	state *clusterState
+}
+
+// Schedule implements the scheduler.Scheduler interface.
+func (s *clusterStateScheduler) Schedule(clock clock.Clock, podQueue queue.PodQueue,
+	nodeLister algorithm.NodeLister, nodeInfoMap map[string]*nodeinfo.NodeInfo) ([]scheduler.Event, error) {
+
+	s.state.nodeInfoMap = nodeInfoMap
+	return s.Scheduler.Schedule(clock, podQueue, nodeLister, nodeInfoMap)
+}
@@ -0,0 +1,89 @@
+package kubesimulator
+
+import (
+	"context"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/clock"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/queue"
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/scheduler"
+	"github.com/pkg/errors"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// completionScheduler stops the simulation once all pods are scheduled, or once the pending pods
+// can't be scheduled anymore. All pods are submitted before the first tick is scheduled and they
+// never finish, so a tick without scheduling events won't be followed by one with events.
+type completionScheduler struct {
+	scheduler.Scheduler
+	predicates map[string]predicates.FitPredicate
+	maxTicks   int
+	cancel     context.CancelFunc
+
+	ticks            int
+	done             bool
+	exceededMaxTicks bool
+	nodeInfoMap      map[string]*nodeinfo.NodeInfo
+	unscheduledPods  []UnscheduledPod
+	err              error
+}
+
+// Schedule implements the scheduler.Scheduler interface.
+func (s *completionScheduler) Schedule(clock clock.Clock, podQueue queue.PodQueue,
+	nodeLister algorithm.NodeLister, nodeInfoMap map[string]*nodeinfo.NodeInfo) ([]scheduler.Event, error) {
+
+	if s.done {
+		return []scheduler.Event{}, nil
+	}
+
+	events, err := s.Scheduler.Schedule(clock, podQueue, nodeLister, nodeInfoMap)
+	if err != nil {
+		// kubesim doesn't return errors of the scheduler, so they're kept until the simulation stops
+		s.stop(errors.Wrap(err, "failed to schedule pods"))
+		return nil, err
+	}
+
+	s.ticks++
+	s.nodeInfoMap = nodeInfoMap
+	pendingPods := podQueue.Metrics().PendingPodsNum
+
+	switch {
+	case pendingPods == 0:
+		s.stop(nil)
+	case len(events) == 0:
+		s.stop(s.diagnose(podQueue, nodeInfoMap))
+	case s.ticks >= s.maxTicks:
+		s.exceededMaxTicks = true
+		s.stop(s.diagnose(podQueue, nodeInfoMap))
+	}
+
+	return events, nil
+}
+
+// diagnose empties the queue and records why its pods can't be scheduled.
+func (s *completionScheduler) diagnose(podQueue queue.PodQueue, nodeInfoMap map[string]*nodeinfo.NodeInfo) error {
+	for {
+		pod, err := podQueue.Pop()
+		if err == queue.ErrEmptyQueue {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "could not pop pending pod")
+		}
+
+		unscheduledPod, err := diagnose(pod, s.predicates, nodeInfoMap)
+		if err != nil {
+			return err
+		}
+
+		s.unscheduledPods = append(s.unscheduledPods, unscheduledPod)
+	}
+}
+
+// stop stops the simulation after the current tick.
+func (s *completionScheduler) stop(err error) {
+	s.done = true
+	s.err = err
+	s.cancel()
+}
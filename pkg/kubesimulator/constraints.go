@@ -0,0 +1,32 @@
+package kubesimulator
+
+import (
+	"fmt"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	v1helper "k8s.io/kubernetes/pkg/apis/core/v1/helper"
+)
+
+// CheckNodeConstraints checks whether the node selector and tolerations of a pod allow it to
+// run on a node. Returns an empty string if they do, or the reason if they don't.
+func CheckNodeConstraints(pod *v1.Pod, node *config.NodeConfig) string {
+	selector := labels.SelectorFromSet(pod.Spec.NodeSelector)
+	if !selector.Matches(labels.Set(node.Metadata.Labels)) {
+		return fmt.Sprintf("node selector %s doesn't match", selector.String())
+	}
+
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == v1.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		if !v1helper.TolerationsTolerateTaint(pod.Spec.Tolerations, taint) {
+			return fmt.Sprintf("taint %s isn't tolerated", taint.ToString())
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,71 @@
+package kubesimulator
+
+import (
+	"fmt"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+	v1 "k8s.io/api/core/v1"
+)
+
+// expandDaemonSetPods replaces pods owned by a DaemonSet with a pod for every node that matches
+// its node selector and tolerations. Like the DaemonSet controller, each pod is bound to its node
+// using node affinity, so it's still scheduled by the scheduler.
+func expandDaemonSetPods(pods []*v1.Pod, nodeConfigs []config.NodeConfig) []*v1.Pod {
+	result := []*v1.Pod{}
+
+	for _, pod := range pods {
+		if !IsDaemonSetPod(pod) {
+			result = append(result, pod)
+			continue
+		}
+
+		for i := range nodeConfigs {
+			if CheckNodeConstraints(pod, &nodeConfigs[i]) != "" {
+				continue
+			}
+
+			nodeName := nodeConfigs[i].Metadata.Name
+			nodePod := pod.DeepCopy()
+			nodePod.Name = fmt.Sprintf("%s-%s", pod.Name, nodeName)
+			setNodeAffinity(nodePod, nodeName)
+
+			result = append(result, nodePod)
+		}
+	}
+
+	return result
+}
+
+// IsDaemonSetPod returns true if the pod is owned by a DaemonSet.
+func IsDaemonSetPod(pod *v1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setNodeAffinity requires the pod to be scheduled on the given node.
+func setNodeAffinity(pod *v1.Pod, nodeName string) {
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &v1.Affinity{}
+	}
+
+	pod.Spec.Affinity.NodeAffinity = &v1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+			NodeSelectorTerms: []v1.NodeSelectorTerm{
+				{
+					MatchFields: []v1.NodeSelectorRequirement{
+						{
+							Key:      "metadata.name",
+							Operator: v1.NodeSelectorOpIn,
+							Values:   []string{nodeName},
+						},
+					},
+				},
+			},
+		},
+	}
+}
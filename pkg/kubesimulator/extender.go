@@ -1,15 +1,27 @@
 package kubesimulator
 
 import (
+	"sort"
+
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/api"
 )
 
 func filterExtender(args api.ExtenderArgs) api.ExtenderFilterResult {
-	// Filters out no nodes.
+	// Filters out no nodes, but sorts them. Predicates run in parallel, so nodes are passed in
+	// a random order, and the scheduler selects one of the best nodes by its position.
+	nodeNames := append([]string{}, *args.NodeNames...)
+	sort.Slice(nodeNames, func(i, j int) bool {
+		// node-2 comes before node-10
+		if len(nodeNames[i]) != len(nodeNames[j]) {
+			return len(nodeNames[i]) < len(nodeNames[j])
+		}
+		return nodeNames[i] < nodeNames[j]
+	})
+
 	return api.ExtenderFilterResult{
 		Nodes:       &v1.NodeList{},
-		NodeNames:   args.NodeNames,
+		NodeNames:   &nodeNames,
 		FailedNodes: api.FailedNodesMap{},
 		Error:       "",
 	}
@@ -0,0 +1,128 @@
+package kubesimulator
+
+import (
+	"fmt"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// NodePlacement is a simulated node and the pods that were scheduled on it.
+type NodePlacement struct {
+	Name         string
+	InstanceType string
+	Zone         string
+
+	Allocatable v1.ResourceList
+	// Allocated is the sum of the requests of the pods, and their number as the "pods" resource.
+	Allocated v1.ResourceList
+	// Pods are the names of the pods, as namespace/name.
+	Pods []string
+}
+
+// Free returns the allocatable resources of the node that aren't allocated.
+func (n *NodePlacement) Free() v1.ResourceList {
+	free := v1.ResourceList{}
+	for name, allocatable := range n.Allocatable {
+		quantity := allocatable.DeepCopy()
+		quantity.Sub(n.Allocated[name])
+		free[name] = quantity
+	}
+
+	return free
+}
+
+// placements returns the nodes of the cluster, in the order of their configs, with their pods.
+func placements(nodeConfigs []config.NodeConfig, nodeInfoMap map[string]*nodeinfo.NodeInfo) []NodePlacement {
+	nodes := []NodePlacement{}
+	for _, nodeConfig := range nodeConfigs {
+		info, ok := nodeInfoMap[nodeConfig.Metadata.Name]
+		if !ok || info.Node() == nil {
+			continue
+		}
+
+		node := NodePlacement{
+			Name:         nodeConfig.Metadata.Name,
+			InstanceType: nodeConfig.Metadata.Labels["node.kubernetes.io/instance-type"],
+			Zone:         nodeConfig.Metadata.Labels["topology.kubernetes.io/zone"],
+			Allocatable:  info.Node().Status.Allocatable.DeepCopy(),
+			Allocated:    v1.ResourceList{},
+			Pods:         []string{},
+		}
+
+		for _, pod := range info.Pods() {
+			for name, quantity := range PodRequests(pod) {
+				total := node.Allocated[name]
+				total.Add(quantity)
+				node.Allocated[name] = total
+			}
+
+			node.Pods = append(node.Pods, fmt.Sprintf("%s/%s", pod.Namespace, pod.Name))
+		}
+		node.Allocated[v1.ResourcePods] = *resource.NewQuantity(int64(len(node.Pods)), resource.DecimalSI)
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// strandedCapacity returns the free resources of the nodes that none of the pods fit on anymore,
+// e.g CPU of a node whose memory is fully allocated. DaemonSet pods are ignored, since they only
+// run on their own node.
+func strandedCapacity(nodes []NodePlacement, pods []*v1.Pod) v1.ResourceList {
+	// Pods with the same requests fit on the same nodes
+	requests := map[string]v1.ResourceList{}
+	for _, pod := range pods {
+		if IsDaemonSetPod(pod) {
+			continue
+		}
+
+		podRequests := PodRequests(pod)
+		podRequests[v1.ResourcePods] = *resource.NewQuantity(1, resource.DecimalSI)
+		requests[fmt.Sprint(podRequests)] = podRequests
+	}
+
+	stranded := v1.ResourceList{}
+	for _, node := range nodes {
+		free := node.Free()
+
+		podFits := false
+		for _, podRequests := range requests {
+			if fits(podRequests, free) {
+				podFits = true
+				break
+			}
+		}
+
+		if podFits {
+			continue
+		}
+
+		for name, quantity := range free {
+			total := stranded[name]
+			total.Add(quantity)
+			stranded[name] = total
+		}
+	}
+
+	return stranded
+}
+
+// fits returns true if the requests are at most the free resources.
+func fits(requests v1.ResourceList, free v1.ResourceList) bool {
+	for name, quantity := range requests {
+		if quantity.IsZero() {
+			continue
+		}
+
+		freeQuantity, ok := free[name]
+		if !ok || quantity.Cmp(freeQuantity) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,29 @@
+package kubesimulator
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// PodRequests returns the effective resource requests of a pod, the way kube-scheduler
+// calculates them: the sum of the containers, or the largest init container if it's larger.
+// Pod overhead is already part of the containers (see the manifests package).
+func PodRequests(pod *v1.Pod) v1.ResourceList {
+	requests := v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		for name, quantity := range container.Resources.Requests {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, ok := requests[name]; !ok || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+
+	return requests
+}
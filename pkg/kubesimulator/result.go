@@ -0,0 +1,79 @@
+package kubesimulator
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// SimulationResult is the outcome of a simulation.
+type SimulationResult struct {
+	// Successful is true if all pods were scheduled.
+	Successful bool
+
+	// UnscheduledPods are the pods that are still pending. The scheduler stops at the first pod
+	// that doesn't fit on any node, so the pods after it are pending even if they fit.
+	UnscheduledPods []UnscheduledPod
+	// Diagnosis is why the simulation stopped before all pods could be scheduled, other than
+	// pods that don't fit, e.g "simulation exceeded 1000 ticks".
+	Diagnosis string
+
+	// Nodes are the nodes of the cluster and the pods that were scheduled on them.
+	Nodes []NodePlacement
+	// StrandedCapacity is the free capacity of nodes that none of the pods fit on anymore.
+	StrandedCapacity v1.ResourceList
+}
+
+// UnscheduledPod is a pod that couldn't be scheduled, and why.
+type UnscheduledPod struct {
+	Namespace string
+	Name      string
+	Requests  v1.ResourceList
+
+	// Reasons are the predicate failure reasons of every node the pod doesn't fit on, by node name
+	// (e.g "Insufficient cpu"). Nodes the pod fits on aren't included.
+	Reasons map[string][]string
+}
+
+// diagnose returns why a pod doesn't fit on each of the nodes. Unlike the scheduler, which
+// stops at the first failing predicate, the reasons of all predicates are returned.
+func diagnose(pod *v1.Pod, preds map[string]predicates.FitPredicate,
+	nodeInfoMap map[string]*nodeinfo.NodeInfo) (UnscheduledPod, error) {
+
+	unscheduledPod := UnscheduledPod{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Requests:  PodRequests(pod),
+		Reasons:   map[string][]string{},
+	}
+
+	names := []string{}
+	for name := range preds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for nodeName, info := range nodeInfoMap {
+		for _, name := range names {
+			fits, failureReasons, err := preds[name](pod, nil, info)
+			if err != nil {
+				return unscheduledPod, errors.Wrapf(err, "could not run predicate %s", name)
+			}
+			if fits {
+				continue
+			}
+
+			if len(failureReasons) == 0 {
+				unscheduledPod.Reasons[nodeName] = append(unscheduledPod.Reasons[nodeName], name)
+			}
+			for _, reason := range failureReasons {
+				unscheduledPod.Reasons[nodeName] = append(unscheduledPod.Reasons[nodeName], reason.GetReason())
+			}
+		}
+	}
+
+	return unscheduledPod, nil
+}
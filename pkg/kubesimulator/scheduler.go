@@ -1,12 +1,15 @@
 package kubesimulator
 
 import (
+	"github.com/aporia-ai/kubesurvival/v2/pkg/topologyspread"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/scheduler"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
 	"k8s.io/kubernetes/pkg/scheduler/algorithm/priorities"
 )
 
-func buildScheduler() scheduler.Scheduler {
+// buildScheduler returns a scheduler for the pods, and the predicates it filters nodes with.
+func buildScheduler(pods []*v1.Pod) (scheduler.Scheduler, map[string]predicates.FitPredicate) {
 	// 1. Create a generic scheduler that mimics a kube-scheduler.
 	sched := scheduler.NewGenericScheduler( /* preemption enabled */ true)
 
@@ -23,7 +26,24 @@ func buildScheduler() scheduler.Scheduler {
 
 	// 2. Register plugin(s)
 	// Predicate
-	sched.AddPredicate("GeneralPredicates", predicates.GeneralPredicates)
+	preds := map[string]predicates.FitPredicate{
+		"GeneralPredicates":      predicates.GeneralPredicates,
+		"PodToleratesNodeTaints": predicates.PodToleratesNodeTaints,
+	}
+
+	// Predicates that look at other pods are slow, so they're only registered if they're needed
+	state := &clusterState{}
+	if hasPodAffinity(pods) {
+		preds["MatchInterPodAffinity"] = predicates.NewPodAffinityPredicate(state, state)
+	}
+	if hasTopologySpread(pods) {
+		preds["PodTopologySpread"] = newTopologySpreadPredicate(state)
+	}
+
+	for name, predicate := range preds {
+		sched.AddPredicate(name, predicate)
+	}
+
 	// Prioritizer
 	sched.AddPrioritizer(priorities.PriorityConfig{
 		Name:   "BalancedResourceAllocation",
@@ -37,6 +57,35 @@ func buildScheduler() scheduler.Scheduler {
 		Reduce: nil,
 		Weight: 1,
 	})
+	sched.AddPrioritizer(priorities.PriorityConfig{
+		Name:   "TaintTolerationPriority",
+		Map:    priorities.ComputeTaintTolerationPriorityMap,
+		Reduce: priorities.ComputeTaintTolerationPriorityReduce,
+		Weight: 1,
+	})
+
+	return &clusterStateScheduler{Scheduler: &sched, state: state}, preds
+}
+
+// hasPodAffinity returns true if any pod has pod affinity or anti-affinity.
+func hasPodAffinity(pods []*v1.Pod) bool {
+	for _, pod := range pods {
+		affinity := pod.Spec.Affinity
+		if affinity != nil && (affinity.PodAffinity != nil || affinity.PodAntiAffinity != nil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasTopologySpread returns true if any pod has topology spread constraints.
+func hasTopologySpread(pods []*v1.Pod) bool {
+	for _, pod := range pods {
+		if _, ok := pod.Annotations[topologyspread.Annotation]; ok {
+			return true
+		}
+	}
 
-	return &sched
+	return false
 }
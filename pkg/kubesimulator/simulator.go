@@ -3,7 +3,6 @@ package kubesimulator
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
 	kubesim "github.com/pfnet-research/k8s-cluster-simulator/pkg"
@@ -13,22 +12,49 @@ import (
 	v1 "k8s.io/api/core/v1"
 )
 
+// DefaultMaxTicks is the default safety cap on the number of ticks of a simulation.
+const DefaultMaxTicks = 1000
+
+// startClock is the simulated start time, so identical inputs give identical results.
+const startClock = "2021-01-01T00:00:00Z"
+
 type KubernetesSimulator struct {
+	// MaxTicks limits the number of ticks a simulation may run for (default: DefaultMaxTicks).
+	MaxTicks int
 }
 
-func (s *KubernetesSimulator) Simulate(pods []*v1.Pod, nodes []nodesource.Node) (bool, error) {
+// Simulate schedules the pods on the nodes.
+func (s *KubernetesSimulator) Simulate(pods []*v1.Pod, nodes []nodesource.Node) (*SimulationResult, error) {
 	queue := queue.NewPriorityQueue()
-	sched := buildScheduler()
 
 	nodeConfigs := []config.NodeConfig{}
 	for i, node := range nodes {
 		nodeName := fmt.Sprintf("node-%d", i)
 		nodeConfigs = append(nodeConfigs, *node.GetNodeConfig(nodeName))
 	}
+	spreadAcrossZones(nodeConfigs)
+
+	pods = expandDaemonSetPods(pods, nodeConfigs)
+
+	maxTicks := s.MaxTicks
+	if maxTicks <= 0 {
+		maxTicks = DefaultMaxTicks
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	genericScheduler, preds := buildScheduler(pods)
+	sched := &completionScheduler{
+		Scheduler:  genericScheduler,
+		predicates: preds,
+		maxTicks:   maxTicks,
+		cancel:     cancel,
+	}
 
 	clusterConfig := &config.Config{
 		LogLevel:      "info",
-		StartClock:    time.Now().Format(time.RFC3339),
+		StartClock:    startClock,
 		Tick:          10,
 		MetricsTick:   60,
 		MetricsLogger: []config.MetricsLoggerConfig{},
@@ -37,18 +63,36 @@ func (s *KubernetesSimulator) Simulate(pods []*v1.Pod, nodes []nodesource.Node)
 
 	kubesim, err := kubesim.NewKubeSim(clusterConfig, queue, sched)
 	if err != nil {
-		return false, errors.Wrap(err, "failed to create kubesim")
+		return nil, errors.Wrap(err, "failed to create kubesim")
 	}
 
 	kubesim.AddSubmitter("Submitter", newSubmitter(pods))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
-	defer cancel()
-
 	err = kubesim.Run(ctx)
-	if err != nil && errors.Cause(err) != context.DeadlineExceeded {
-		return false, errors.Wrap(err, "failed to run kubesim")
+	if err != nil && errors.Cause(err) != context.Canceled {
+		return nil, errors.Wrap(err, "failed to run kubesim")
+	}
+
+	if sched.err != nil {
+		return nil, sched.err
+	}
+	if !sched.done {
+		return nil, errors.New("kubesim stopped before all pods were scheduled")
+	}
+
+	nodePlacements := placements(nodeConfigs, sched.nodeInfoMap)
+
+	// A cluster that can't be simulated within the max ticks is rejected, so the search goes on
+	diagnosis := ""
+	if sched.exceededMaxTicks {
+		diagnosis = fmt.Sprintf("simulation exceeded %d ticks", maxTicks)
 	}
 
-	return errors.Cause(err) != context.DeadlineExceeded && (queue.Metrics().PendingPodsNum == 0), nil
+	return &SimulationResult{
+		Successful:       len(sched.unscheduledPods) == 0 && !sched.exceededMaxTicks,
+		UnscheduledPods:  sched.unscheduledPods,
+		Diagnosis:        diagnosis,
+		Nodes:            nodePlacements,
+		StrandedCapacity: strandedCapacity(nodePlacements, pods),
+	}, nil
 }
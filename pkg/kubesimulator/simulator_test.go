@@ -0,0 +1,146 @@
+package kubesimulator_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/kubesimulator"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/podgen/podgentest"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// newNodes returns count nodes with the given allocatable resources.
+func newNodes(cpu string, memory string, count int) []nodesource.Node {
+	node := &nodesource.StaticNode{
+		Name:    "node",
+		CPU:     resource.MustParse(cpu),
+		Memory:  resource.MustParse(memory),
+		MaxPods: 110,
+		Region:  "on-prem",
+	}
+
+	nodes := []nodesource.Node{}
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+func TestSimulate(t *testing.T) {
+	simulator := &kubesimulator.KubernetesSimulator{}
+
+	result, err := simulator.Simulate(podgentest.Pods(t, `pod(cpu: 1, memory: "1Gi") * 4`), newNodes("2", "8Gi", 2))
+	assert.Nil(t, err)
+	assert.True(t, result.Successful)
+	assert.Empty(t, result.UnscheduledPods)
+	assert.Empty(t, result.Diagnosis)
+
+	// Nodes are spread across the zones of their region
+	assert.Len(t, result.Nodes, 2)
+	assert.Equal(t, "on-prem-a", result.Nodes[0].Zone)
+	assert.Equal(t, "on-prem-b", result.Nodes[1].Zone)
+	assert.Len(t, result.Nodes[0].Pods, 2)
+	assert.Len(t, result.Nodes[1].Pods, 2)
+}
+
+func TestSimulateUnscheduledPods(t *testing.T) {
+	simulator := &kubesimulator.KubernetesSimulator{}
+
+	// The simulation stops once the pending pod can't be scheduled anymore
+	result, err := simulator.Simulate(podgentest.Pods(t, `pod(cpu: 1, memory: "1Gi") * 2 + pod(cpu: 4, memory: "1Gi")`),
+		newNodes("2", "8Gi", 2))
+	assert.Nil(t, err)
+	assert.False(t, result.Successful)
+	assert.Empty(t, result.Diagnosis)
+
+	// The pods after the first one that doesn't fit are pending as well
+	assert.Len(t, result.UnscheduledPods, 2)
+	assert.Equal(t, "pod-2", result.UnscheduledPods[0].Name)
+	assert.Equal(t, []string{"Insufficient cpu"}, result.UnscheduledPods[0].Reasons["node-0"])
+	assert.Empty(t, result.UnscheduledPods[1].Reasons)
+}
+
+func TestSimulateTopologySpread(t *testing.T) {
+	// A large node in one zone and a small node in another, so the pods can only be spread
+	// unevenly across the zones
+	nodes := []nodesource.Node{
+		&nodesource.StaticNode{Name: "large", CPU: resource.MustParse("4"), Memory: resource.MustParse("16Gi"), MaxPods: 110, Zone: "a"},
+		&nodesource.StaticNode{Name: "small", CPU: resource.MustParse("1"), Memory: resource.MustParse("16Gi"), MaxPods: 110, Zone: "b"},
+	}
+
+	tests := []struct {
+		name            string
+		maxSkew         int
+		unscheduledPods int
+	}{
+		// 2 pods in zone a and 1 in zone b
+		{name: "max skew violated", maxSkew: 1, unscheduledPods: 1},
+		// 3 pods in zone a and 1 in zone b
+		{name: "max skew satisfied", maxSkew: 2, unscheduledPods: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			simulator := &kubesimulator.KubernetesSimulator{}
+			pods := podgentest.Pods(t, fmt.Sprintf(`spread(pod(cpu: 1, memory: "1Gi") * 4, by: "zone", maxSkew: %d)`, test.maxSkew))
+
+			result, err := simulator.Simulate(pods, nodes)
+			assert.Nil(t, err)
+			assert.Equal(t, test.unscheduledPods == 0, result.Successful)
+			assert.Len(t, result.UnscheduledPods, test.unscheduledPods)
+
+			for _, pod := range result.UnscheduledPods {
+				assert.Equal(t, map[string][]string{
+					"node-0": {"node(s) didn't match pod topology spread constraints"},
+					"node-1": {"Insufficient cpu"},
+				}, pod.Reasons)
+			}
+		})
+	}
+}
+
+func TestSimulateMaxTicks(t *testing.T) {
+	simulator := &kubesimulator.KubernetesSimulator{MaxTicks: 1}
+
+	// Pods are still being scheduled after the first tick
+	result, err := simulator.Simulate(podgentest.Pods(t, `pod(cpu: 1, memory: "1Gi") * 4`), newNodes("2", "8Gi", 1))
+	assert.Nil(t, err)
+	assert.False(t, result.Successful)
+	assert.Equal(t, "simulation exceeded 1 ticks", result.Diagnosis)
+}
+
+func TestSimulateIsDeterministic(t *testing.T) {
+	simulator := &kubesimulator.KubernetesSimulator{}
+	pods := podgentest.Pods(t, `pod(cpu: "700m", memory: "1Gi") * 9 + pod(cpu: "300m", memory: "3Gi") * 7`)
+
+	first, err := simulator.Simulate(pods, newNodes("4", "16Gi", 3))
+	assert.Nil(t, err)
+
+	second, err := simulator.Simulate(pods, newNodes("4", "16Gi", 3))
+	assert.Nil(t, err)
+
+	assert.True(t, first.Successful)
+	assert.Equal(t, first, second)
+}
+
+func TestSimulateNodeOrder(t *testing.T) {
+	simulator := &kubesimulator.KubernetesSimulator{}
+
+	// The scheduler takes turns between equally good nodes in the order of their number, so
+	// node-2 comes before node-10
+	result, err := simulator.Simulate(podgentest.Pods(t, `pod(cpu: 1, memory: "1Gi") * 3`), newNodes("4", "16Gi", 12))
+	assert.Nil(t, err)
+	assert.True(t, result.Successful)
+
+	nodesWithPods := []string{}
+	for i, node := range result.Nodes {
+		assert.Equal(t, fmt.Sprintf("node-%d", i), node.Name)
+		if len(node.Pods) > 0 {
+			nodesWithPods = append(nodesWithPods, node.Name)
+		}
+	}
+	assert.Equal(t, []string{"node-0", "node-2", "node-4"}, nodesWithPods)
+}
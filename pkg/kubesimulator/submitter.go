@@ -9,6 +9,12 @@ import (
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/submitter"
 )
 
+// simSpec makes pods run for longer than any simulation, since only their scheduling matters.
+const simSpec = `
+- seconds: 2147483647
+  resourceUsage: {}
+`
+
 type Submitter struct {
 	pods []*v1.Pod
 }
@@ -23,10 +29,18 @@ func (s *Submitter) Submit(clock clock.Clock, _ algorithm.NodeLister, met metric
 	events := []submitter.Event{}
 
 	for _, pod := range s.pods {
+		// kubesim modifies submitted pods (e.g binds them to nodes), so the same pods
+		// can't be reused across simulations.
+		pod = pod.DeepCopy()
 		if pod.ObjectMeta.Namespace == "" {
 			pod.ObjectMeta.Namespace = "default"
 		}
 
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations["simSpec"] = simSpec
+
 		events = append(events, &submitter.SubmitEvent{Pod: pod})
 	}
 
@@ -0,0 +1,90 @@
+package kubesimulator
+
+import (
+	"github.com/aporia-ai/kubesurvival/v2/pkg/topologyspread"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/kubernetes/pkg/scheduler/algorithm/predicates"
+	"k8s.io/kubernetes/pkg/scheduler/nodeinfo"
+)
+
+// ErrTopologySpreadConstraintsNotMatch is the failure reason of the topology spread predicate.
+var ErrTopologySpreadConstraintsNotMatch = &predicates.PredicateFailureError{
+	PredicateName: "PodTopologySpread",
+	PredicateDesc: "node(s) didn't match pod topology spread constraints",
+}
+
+// newTopologySpreadPredicate returns a predicate that checks the DoNotSchedule topology spread
+// constraints of a pod, like the PodTopologySpread plugin of newer kube-scheduler versions.
+func newTopologySpreadPredicate(state *clusterState) predicates.FitPredicate {
+	return func(pod *v1.Pod, _ predicates.PredicateMetadata, nodeInfo *nodeinfo.NodeInfo) (bool, []predicates.PredicateFailureReason, error) {
+		constraints, err := topologyspread.Get(pod)
+		if err != nil {
+			return false, nil, err
+		}
+
+		node := nodeInfo.Node()
+		for _, constraint := range constraints {
+			if constraint.WhenUnsatisfiable != "" && constraint.WhenUnsatisfiable != "DoNotSchedule" {
+				continue
+			}
+
+			ok, err := state.satisfiesTopologySpread(pod, node, constraint)
+			if err != nil {
+				return false, nil, err
+			}
+
+			if !ok {
+				return false, []predicates.PredicateFailureReason{ErrTopologySpreadConstraintsNotMatch}, nil
+			}
+		}
+
+		return true, nil, nil
+	}
+}
+
+// satisfiesTopologySpread checks whether placing the pod on the node keeps the difference between
+// the number of matching pods in its topology domain and in the emptiest domain within maxSkew.
+func (c *clusterState) satisfiesTopologySpread(pod *v1.Pod, node *v1.Node, constraint topologyspread.Constraint) (bool, error) {
+	domain, ok := node.Labels[constraint.TopologyKey]
+	if !ok {
+		return false, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+	if err != nil {
+		return false, err
+	}
+
+	// Count matching pods in every domain of the nodes the pod could be scheduled on
+	nodeSelector := labels.SelectorFromSet(pod.Spec.NodeSelector)
+	podsPerDomain := map[string]int{}
+	for _, info := range c.nodeInfoMap {
+		otherNode := info.Node()
+		if otherNode == nil || !nodeSelector.Matches(labels.Set(otherNode.Labels)) {
+			continue
+		}
+
+		otherDomain, ok := otherNode.Labels[constraint.TopologyKey]
+		if !ok {
+			continue
+		}
+
+		podsPerDomain[otherDomain] += 0
+		for _, otherPod := range info.Pods() {
+			if otherPod.Namespace == pod.Namespace && selector.Matches(labels.Set(otherPod.Labels)) {
+				podsPerDomain[otherDomain]++
+			}
+		}
+	}
+
+	minPods := -1
+	for _, count := range podsPerDomain {
+		if minPods == -1 || count < minPods {
+			minPods = count
+		}
+	}
+
+	return podsPerDomain[domain]+1-minPods <= int(constraint.MaxSkew), nil
+}
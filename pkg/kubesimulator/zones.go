@@ -0,0 +1,48 @@
+package kubesimulator
+
+import (
+	"fmt"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+)
+
+// zonesPerRegion is the number of availability zones nodes are spread across.
+const zonesPerRegion = 3
+
+// spreadAcrossZones sets the zone label of nodes with a region label. Like a node group
+// that spans several zones, nodes of each instance type are spread evenly across zones.
+func spreadAcrossZones(nodeConfigs []config.NodeConfig) {
+	nodesPerInstanceType := map[string]int{}
+
+	for i := range nodeConfigs {
+		labels := nodeConfigs[i].Metadata.Labels
+		region := labels["topology.kubernetes.io/region"]
+		if region == "" || labels["topology.kubernetes.io/zone"] != "" {
+			continue
+		}
+
+		instanceType := labels["node.kubernetes.io/instance-type"]
+		zone := zoneName(region, nodesPerInstanceType[instanceType]%zonesPerRegion, labels)
+		nodesPerInstanceType[instanceType]++
+
+		labels["topology.kubernetes.io/zone"] = zone
+		labels["failure-domain.beta.kubernetes.io/zone"] = zone
+	}
+}
+
+// zoneName returns the name of the i-th zone of a region, which is named like us-east-1a on AWS,
+// us-central1-a on GCP and eastus-1 on Azure. Zones of other regions, e.g the on-prem region of
+// static nodes, are named like on-prem-a.
+func zoneName(region string, i int, labels map[string]string) string {
+	if labels["cloud.google.com/gke-nodepool"] != "" {
+		return fmt.Sprintf("%s-%c", region, 'a'+i)
+	}
+	if labels["kubernetes.azure.com/agentpool"] != "" {
+		return fmt.Sprintf("%s-%d", region, i+1)
+	}
+	if last := region[len(region)-1]; last < '0' || last > '9' {
+		return fmt.Sprintf("%s-%c", region, 'a'+i)
+	}
+
+	return fmt.Sprintf("%s%c", region, 'a'+i)
+}
@@ -49,7 +49,7 @@ func (s *Scanner) Scan() Token {
 		ch, pos = s.read()
 	}
 
-	// If we see a letter then consume as an keyword.
+	// If we see a letter then consume as an keyword or identifier.
 	if isLetter(ch) {
 		s.Unscan()
 		return s.scanKeyword()
@@ -78,6 +78,21 @@ func (s *Scanner) Scan() Token {
 	case ':':
 		return Token{TokenType: COLON, Lexeme: string(ch), Position: pos}
 
+	case '=':
+		return Token{TokenType: ASSIGN, Lexeme: string(ch), Position: pos}
+
+	case '[':
+		return Token{TokenType: LBRACKET, Lexeme: string(ch), Position: pos}
+
+	case ']':
+		return Token{TokenType: RBRACKET, Lexeme: string(ch), Position: pos}
+
+	case '{':
+		return Token{TokenType: LBRACE, Lexeme: string(ch), Position: pos}
+
+	case '}':
+		return Token{TokenType: RBRACE, Lexeme: string(ch), Position: pos}
+
 	case '+':
 		return Token{TokenType: ADD, Lexeme: string(ch), Position: pos}
 
@@ -103,6 +118,7 @@ func (s *Scanner) scanWhitespace() {
 }
 
 // scanKeyword consumes the current rune and all contiguous identifier runes.
+// Returns a keyword token if the identifier is a keyword, or an IDENT token otherwise.
 func (s *Scanner) scanKeyword() Token {
 	ch, pos := s.read()
 
@@ -133,9 +149,30 @@ func (s *Scanner) scanKeyword() Token {
 		return Token{TokenType: MEMORY, Lexeme: buf.String(), Position: pos}
 	case "gpu":
 		return Token{TokenType: GPU, Lexeme: buf.String(), Position: pos}
+	case "let":
+		return Token{TokenType: LET, Lexeme: buf.String(), Position: pos}
+	case "def":
+		return Token{TokenType: DEF, Lexeme: buf.String(), Position: pos}
+	case "container":
+		return Token{TokenType: CONTAINER, Lexeme: buf.String(), Position: pos}
+	case "containers":
+		return Token{TokenType: CONTAINERS, Lexeme: buf.String(), Position: pos}
+	case "init":
+		return Token{TokenType: INIT, Lexeme: buf.String(), Position: pos}
+	case "nodeSelector":
+		return Token{TokenType: NODESELECTOR, Lexeme: buf.String(), Position: pos}
+	case "tolerations":
+		return Token{TokenType: TOLERATIONS, Lexeme: buf.String(), Position: pos}
+	case "spread":
+		return Token{TokenType: SPREAD, Lexeme: buf.String(), Position: pos}
+	case "by":
+		return Token{TokenType: BY, Lexeme: buf.String(), Position: pos}
+	case "maxSkew":
+		return Token{TokenType: MAXSKEW, Lexeme: buf.String(), Position: pos}
 	}
 
-	return Token{TokenType: ILLEGAL, Lexeme: buf.String(), Position: pos}
+	// Otherwise, it's an identifier.
+	return Token{TokenType: IDENT, Lexeme: buf.String(), Position: pos}
 }
 
 // scanInteger consumes a contiguous series of digits.
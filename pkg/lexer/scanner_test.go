@@ -73,17 +73,115 @@ func TestScannerKeywords(t *testing.T) {
 	assertToken(t, s, lexer.GPU, "gpu")
 	assertToken(t, s, lexer.GPU, "gpu")
 	assertToken(t, s, lexer.POD, "pod")
-	assertToken(t, s, lexer.ILLEGAL, "da")
+	assertToken(t, s, lexer.IDENT, "da")
+	assertToken(t, s, lexer.EOF, "EOF")
+}
+
+func TestScannerLet(t *testing.T) {
+	s := lexer.NewScanner(strings.NewReader(`let api_server2 = pod() lets`))
+	assertToken(t, s, lexer.LET, "let")
+	assertToken(t, s, lexer.IDENT, "api_server2")
+	assertToken(t, s, lexer.ASSIGN, "=")
+	assertToken(t, s, lexer.POD, "pod")
+	assertToken(t, s, lexer.LPAREN, "(")
+	assertToken(t, s, lexer.RPAREN, ")")
+	assertToken(t, s, lexer.IDENT, "lets")
+	assertToken(t, s, lexer.EOF, "EOF")
+}
+
+func TestScannerDef(t *testing.T) {
+	s := lexer.NewScanner(strings.NewReader(`def svc(cpu, replicas) = pod(cpu: cpu) * replicas`))
+	assertToken(t, s, lexer.DEF, "def")
+	assertToken(t, s, lexer.IDENT, "svc")
+	assertToken(t, s, lexer.LPAREN, "(")
+	assertToken(t, s, lexer.CPU, "cpu")
+	assertToken(t, s, lexer.COMMA, ",")
+	assertToken(t, s, lexer.IDENT, "replicas")
+	assertToken(t, s, lexer.RPAREN, ")")
+	assertToken(t, s, lexer.ASSIGN, "=")
+	assertToken(t, s, lexer.POD, "pod")
+	assertToken(t, s, lexer.LPAREN, "(")
+	assertToken(t, s, lexer.CPU, "cpu")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.CPU, "cpu")
+	assertToken(t, s, lexer.RPAREN, ")")
+	assertToken(t, s, lexer.MUL, "*")
+	assertToken(t, s, lexer.IDENT, "replicas")
+	assertToken(t, s, lexer.EOF, "EOF")
+}
+
+func TestScannerSpread(t *testing.T) {
+	s := lexer.NewScanner(strings.NewReader(`spread(api * 3, by: "zone", maxSkew: 1)`))
+	assertToken(t, s, lexer.SPREAD, "spread")
+	assertToken(t, s, lexer.LPAREN, "(")
+	assertToken(t, s, lexer.IDENT, "api")
+	assertToken(t, s, lexer.MUL, "*")
+	assertToken(t, s, lexer.INTEGER, "3")
+	assertToken(t, s, lexer.COMMA, ",")
+	assertToken(t, s, lexer.BY, "by")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.STRING, "zone")
+	assertToken(t, s, lexer.COMMA, ",")
+	assertToken(t, s, lexer.MAXSKEW, "maxSkew")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.INTEGER, "1")
+	assertToken(t, s, lexer.RPAREN, ")")
 	assertToken(t, s, lexer.EOF, "EOF")
 }
 
 func TestScannerSymbols(t *testing.T) {
-	s := lexer.NewScanner(strings.NewReader(`(),,    :`))
+	s := lexer.NewScanner(strings.NewReader(`(),,    : = [] {}`))
 	assertToken(t, s, lexer.LPAREN, "(")
 	assertToken(t, s, lexer.RPAREN, ")")
 	assertToken(t, s, lexer.COMMA, ",")
 	assertToken(t, s, lexer.COMMA, ",")
 	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.ASSIGN, "=")
+	assertToken(t, s, lexer.LBRACKET, "[")
+	assertToken(t, s, lexer.RBRACKET, "]")
+	assertToken(t, s, lexer.LBRACE, "{")
+	assertToken(t, s, lexer.RBRACE, "}")
+	assertToken(t, s, lexer.EOF, "EOF")
+}
+
+func TestScannerScheduling(t *testing.T) {
+	s := lexer.NewScanner(strings.NewReader(`nodeSelector: {"gpu": "true"}, tolerations: ["gpu"]`))
+	assertToken(t, s, lexer.NODESELECTOR, "nodeSelector")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.LBRACE, "{")
+	assertToken(t, s, lexer.STRING, "gpu")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.STRING, "true")
+	assertToken(t, s, lexer.RBRACE, "}")
+	assertToken(t, s, lexer.COMMA, ",")
+	assertToken(t, s, lexer.TOLERATIONS, "tolerations")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.LBRACKET, "[")
+	assertToken(t, s, lexer.STRING, "gpu")
+	assertToken(t, s, lexer.RBRACKET, "]")
+	assertToken(t, s, lexer.EOF, "EOF")
+}
+
+func TestScannerContainers(t *testing.T) {
+	s := lexer.NewScanner(strings.NewReader(`pod(containers: [container(cpu: 1)], init: [])`))
+	assertToken(t, s, lexer.POD, "pod")
+	assertToken(t, s, lexer.LPAREN, "(")
+	assertToken(t, s, lexer.CONTAINERS, "containers")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.LBRACKET, "[")
+	assertToken(t, s, lexer.CONTAINER, "container")
+	assertToken(t, s, lexer.LPAREN, "(")
+	assertToken(t, s, lexer.CPU, "cpu")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.INTEGER, "1")
+	assertToken(t, s, lexer.RPAREN, ")")
+	assertToken(t, s, lexer.RBRACKET, "]")
+	assertToken(t, s, lexer.COMMA, ",")
+	assertToken(t, s, lexer.INIT, "init")
+	assertToken(t, s, lexer.COLON, ":")
+	assertToken(t, s, lexer.LBRACKET, "[")
+	assertToken(t, s, lexer.RBRACKET, "]")
+	assertToken(t, s, lexer.RPAREN, ")")
 	assertToken(t, s, lexer.EOF, "EOF")
 }
 
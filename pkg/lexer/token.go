@@ -9,16 +9,34 @@ const (
 	EOF
 
 	// Symbols
-	LPAREN // (
-	RPAREN // )
-	COMMA  // ,
-	COLON  // :
+	LPAREN   // (
+	RPAREN   // )
+	COMMA    // ,
+	COLON    // :
+	ASSIGN   // =
+	LBRACKET // [
+	RBRACKET // ]
+	LBRACE   // {
+	RBRACE   // }
 
 	// Keywords
 	POD    // pod
 	CPU    // cpu
 	MEMORY // memory
 	GPU    // gpu
+	LET    // let
+	DEF    // def
+
+	CONTAINER  // container
+	CONTAINERS // containers
+	INIT       // init
+
+	NODESELECTOR // nodeSelector
+	TOLERATIONS  // tolerations
+
+	SPREAD  // spread
+	BY      // by
+	MAXSKEW // maxSkew
 
 	// Operators
 	ADD // +
@@ -27,6 +45,7 @@ const (
 	// Literals
 	INTEGER // 5
 	STRING  // "100m"
+	IDENT   // api
 
 	// Errors
 	BADSTRING // "abc
@@ -51,16 +70,34 @@ var tokens = [...]string{
 	EOF:     "EOF",
 
 	// Symbols
-	LPAREN: "(",
-	RPAREN: ")",
-	COMMA:  ",",
-	COLON:  ":",
+	LPAREN:   "(",
+	RPAREN:   ")",
+	COMMA:    ",",
+	COLON:    ":",
+	ASSIGN:   "=",
+	LBRACKET: "[",
+	RBRACKET: "]",
+	LBRACE:   "{",
+	RBRACE:   "}",
 
 	// Keywords
 	POD:    "pod",
 	CPU:    "cpu",
 	MEMORY: "memory",
 	GPU:    "gpu",
+	LET:    "let",
+	DEF:    "def",
+
+	CONTAINER:  "container",
+	CONTAINERS: "containers",
+	INIT:       "init",
+
+	NODESELECTOR: "nodeSelector",
+	TOLERATIONS:  "tolerations",
+
+	SPREAD:  "spread",
+	BY:      "by",
+	MAXSKEW: "maxSkew",
 
 	// Operators
 	ADD: "+",
@@ -69,6 +106,7 @@ var tokens = [...]string{
 	// Literals
 	INTEGER: "INTEGER",
 	STRING:  "STRING",
+	IDENT:   "IDENT",
 
 	// Errors
 	BADSTRING: "BADSTRING",
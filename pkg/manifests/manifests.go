@@ -0,0 +1,368 @@
+package manifests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/admission"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/topologyspread"
+)
+
+// manifest holds the fields that are needed before decoding a manifest into its kind.
+type manifest struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Items of a List
+	Items []json.RawMessage `json:"items"`
+
+	Spec struct {
+		podSpecExtensions `json:",inline"`
+		Template          struct {
+			Spec podSpecExtensions `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+// podSpecExtensions holds pod spec fields that aren't part of the Kubernetes API version
+// used by the simulator, so they're decoded separately.
+type podSpecExtensions struct {
+	Overhead                  corev1.ResourceList         `json:"overhead"`
+	TopologySpreadConstraints []topologyspread.Constraint `json:"topologySpreadConstraints"`
+}
+
+// Load reads Kubernetes manifests from files and directories, and generates the pods
+// they would create. Directories are read non-recursively.
+func Load(paths ...string) ([]*corev1.Pod, error) {
+	pods := []*corev1.Pod{}
+
+	for _, path := range paths {
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not open manifest file")
+			}
+
+			filePods, err := Decode(f)
+			f.Close()
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not load manifest file %s", file)
+			}
+
+			pods = append(pods, filePods...)
+		}
+	}
+
+	return pods, nil
+}
+
+// manifestFiles returns the path if it's a file, or the YAML and JSON files in it if it's a directory.
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read manifests")
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read manifests directory")
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// Decode reads a stream of YAML or JSON Kubernetes manifests, and generates the pods they
+// would create. Kinds that don't create pods (e.g Services) are ignored.
+func Decode(reader io.Reader) ([]*corev1.Pod, error) {
+	pods := []*corev1.Pod{}
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode manifest")
+		}
+
+		manifestPods, err := decodeManifest(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		pods = append(pods, manifestPods...)
+	}
+
+	return pods, nil
+}
+
+// decodeManifest generates the pods of a single manifest.
+func decodeManifest(raw json.RawMessage) ([]*corev1.Pod, error) {
+	// Skip empty documents
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	m := &manifest{}
+	if err := json.Unmarshal(raw, m); err != nil {
+		return nil, errors.Wrap(err, "could not decode manifest")
+	}
+
+	switch m.Kind {
+	case "List":
+		pods := []*corev1.Pod{}
+		for _, item := range m.Items {
+			itemPods, err := decodeManifest(item)
+			if err != nil {
+				return nil, err
+			}
+
+			pods = append(pods, itemPods...)
+		}
+
+		return pods, nil
+
+	case "Pod":
+		pod := &corev1.Pod{}
+		if err := unmarshal(raw, pod); err != nil {
+			return nil, err
+		}
+
+		return single(pod.ObjectMeta, podTemplate(pod), m.Spec.podSpecExtensions, nil)
+
+	case "Deployment":
+		deployment := &appsv1.Deployment{}
+		if err := unmarshal(raw, deployment); err != nil {
+			return nil, err
+		}
+
+		return replicate(deployment.ObjectMeta, deployment.Spec.Template, m.Spec.Template.Spec,
+			replicas(deployment.Spec.Replicas), nil)
+
+	case "StatefulSet":
+		statefulSet := &appsv1.StatefulSet{}
+		if err := unmarshal(raw, statefulSet); err != nil {
+			return nil, err
+		}
+
+		return replicate(statefulSet.ObjectMeta, statefulSet.Spec.Template, m.Spec.Template.Spec,
+			replicas(statefulSet.Spec.Replicas), nil)
+
+	case "ReplicaSet":
+		replicaSet := &appsv1.ReplicaSet{}
+		if err := unmarshal(raw, replicaSet); err != nil {
+			return nil, err
+		}
+
+		return replicate(replicaSet.ObjectMeta, replicaSet.Spec.Template, m.Spec.Template.Spec,
+			replicas(replicaSet.Spec.Replicas), nil)
+
+	case "Job":
+		job := &batchv1.Job{}
+		if err := unmarshal(raw, job); err != nil {
+			return nil, err
+		}
+
+		// Only pods that run in parallel need to fit in the cluster
+		count := replicas(job.Spec.Parallelism)
+		if job.Spec.Completions != nil && int(*job.Spec.Completions) < count {
+			count = int(*job.Spec.Completions)
+		}
+
+		return replicate(job.ObjectMeta, job.Spec.Template, m.Spec.Template.Spec, count, nil)
+
+	case "DaemonSet":
+		daemonSet := &appsv1.DaemonSet{}
+		if err := unmarshal(raw, daemonSet); err != nil {
+			return nil, err
+		}
+
+		// DaemonSets run a pod on every node, so the simulator creates the actual pods
+		// once it knows the nodes.
+		owner := &metav1.OwnerReference{
+			APIVersion: "apps/v1",
+			Kind:       "DaemonSet",
+			Name:       daemonSet.Name,
+		}
+
+		return replicate(daemonSet.ObjectMeta, daemonSet.Spec.Template, m.Spec.Template.Spec, 1, owner)
+
+	default:
+		return nil, nil
+	}
+}
+
+// unmarshal decodes a manifest into a Kubernetes object.
+func unmarshal(raw json.RawMessage, object interface{}) error {
+	if err := json.Unmarshal(raw, object); err != nil {
+		return errors.Wrap(err, "could not decode manifest")
+	}
+
+	return nil
+}
+
+// replicas returns the value of an optional replica count, which defaults to 1.
+func replicas(value *int32) int {
+	if value == nil {
+		return 1
+	}
+
+	return int(*value)
+}
+
+// podTemplate returns the labels and spec of a pod as a pod template.
+func podTemplate(pod *corev1.Pod) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: pod.Labels},
+		Spec:       pod.Spec,
+	}
+}
+
+// single generates the pod of a Pod manifest, which keeps its name unlike replicas.
+func single(meta metav1.ObjectMeta, template corev1.PodTemplateSpec, extensions podSpecExtensions,
+	owner *metav1.OwnerReference) ([]*corev1.Pod, error) {
+
+	pods, err := replicate(meta, template, extensions, 1, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	pods[0].Name = meta.Name
+	return pods, nil
+}
+
+// replicate generates count pods from a pod template.
+func replicate(meta metav1.ObjectMeta, template corev1.PodTemplateSpec, extensions podSpecExtensions,
+	count int, owner *metav1.OwnerReference) ([]*corev1.Pod, error) {
+
+	spec := *template.Spec.DeepCopy()
+	spec.NodeName = ""
+	defaultRequests(spec.Containers)
+	defaultRequests(spec.InitContainers)
+	addOverhead(&spec, extensions.Overhead)
+	admission.AddExtendedResourceTolerations(&spec)
+
+	pods := []*corev1.Pod{}
+	for i := 0; i < count; i++ {
+		pod := &corev1.Pod{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Pod",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", meta.Name, i),
+				Namespace: meta.Namespace,
+				Labels:    copyLabels(template.Labels),
+			},
+			Spec: *spec.DeepCopy(),
+		}
+
+		if owner != nil {
+			pod.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+
+		if err := topologyspread.Add(pod, extensions.TopologySpreadConstraints...); err != nil {
+			return nil, err
+		}
+
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+// copyLabels returns a copy of a label map, or nil if it's empty.
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	copied := map[string]string{}
+	for key, value := range labels {
+		copied[key] = value
+	}
+
+	return copied
+}
+
+// defaultRequests sets container requests to their limits if they're not set,
+// like the Kubernetes API server does.
+func defaultRequests(containers []corev1.Container) {
+	for i := range containers {
+		resources := &containers[i].Resources
+		for name, limit := range resources.Limits {
+			if _, ok := resources.Requests[name]; ok {
+				continue
+			}
+
+			if resources.Requests == nil {
+				resources.Requests = corev1.ResourceList{}
+			}
+			resources.Requests[name] = limit.DeepCopy()
+		}
+	}
+}
+
+// addOverhead folds the pod overhead into the containers, since the scheduler of the Kubernetes API
+// version used by the simulator doesn't know about it. The overhead is added to every init container
+// and as an additional container, so the pod requests max(init containers, containers) + overhead.
+func addOverhead(spec *corev1.PodSpec, overhead corev1.ResourceList) {
+	if len(overhead) == 0 {
+		return
+	}
+
+	for i := range spec.InitContainers {
+		requests := spec.InitContainers[i].Resources.Requests
+		if requests == nil {
+			requests = corev1.ResourceList{}
+		}
+
+		for name, quantity := range overhead {
+			total := requests[name]
+			total.Add(quantity)
+			requests[name] = total
+		}
+
+		spec.InitContainers[i].Resources.Requests = requests
+	}
+
+	spec.Containers = append(spec.Containers, corev1.Container{
+		Name:  "pod-overhead",
+		Image: "pod-overhead",
+		Resources: corev1.ResourceRequirements{
+			Requests: overhead.DeepCopy(),
+		},
+	})
+}
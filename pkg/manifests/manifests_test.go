@@ -0,0 +1,238 @@
+package manifests_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/manifests"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/topologyspread"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDecodeWorkloads(t *testing.T) {
+	pods, err := manifests.Decode(strings.NewReader(`
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+  namespace: prod
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: api
+        resources:
+          requests:
+            cpu: 500m
+      - name: envoy
+        resources:
+          requests:
+            cpu: 100m
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: api
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      containers:
+      - name: db
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  parallelism: 5
+  completions: 2
+  template:
+    spec:
+      containers:
+      - name: migrate
+`))
+
+	assert.NoError(t, err)
+	assert.Len(t, pods, 6)
+
+	assert.Equal(t, "api-0", pods[0].Name)
+	assert.Equal(t, "prod", pods[0].Namespace)
+	assert.Len(t, pods[0].Spec.Containers, 2)
+	assert.Equal(t, "api-2", pods[2].Name)
+	assert.Equal(t, "db-0", pods[3].Name)
+	assert.Equal(t, "migrate-1", pods[5].Name)
+}
+
+func TestDecodeRequestsDefaultToLimits(t *testing.T) {
+	pods, err := manifests.Decode(strings.NewReader(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: worker
+spec:
+  containers:
+  - name: worker
+    resources:
+      requests:
+        cpu: "1"
+      limits:
+        cpu: "2"
+        memory: 1Gi
+`))
+
+	assert.NoError(t, err)
+	assert.Len(t, pods, 1)
+
+	requests := pods[0].Spec.Containers[0].Resources.Requests
+	assert.Equal(t, "1", requests.Cpu().String())
+	assert.Equal(t, "1Gi", requests.Memory().String())
+}
+
+func TestDecodeExtendedResourceTolerations(t *testing.T) {
+	pods, err := manifests.Decode(strings.NewReader(`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: trainer
+spec:
+  containers:
+  - name: trainer
+    resources:
+      limits:
+        nvidia.com/gpu: 1
+`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.Toleration{
+		{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	}, pods[0].Spec.Tolerations)
+}
+
+func TestDecodeOverhead(t *testing.T) {
+	pods, err := manifests.Decode(strings.NewReader(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: sandboxed
+spec:
+  template:
+    spec:
+      overhead:
+        cpu: 250m
+      initContainers:
+      - name: init
+        resources:
+          requests:
+            cpu: "2"
+      containers:
+      - name: app
+        resources:
+          requests:
+            cpu: "1"
+`))
+
+	assert.NoError(t, err)
+	assert.Len(t, pods, 1)
+
+	spec := pods[0].Spec
+	assert.Len(t, spec.Containers, 2)
+	assert.Equal(t, "250m", spec.Containers[1].Resources.Requests.Cpu().String())
+	assert.Equal(t, "2250m", spec.InitContainers[0].Resources.Requests.Cpu().String())
+}
+
+func TestDecodeTopologySpread(t *testing.T) {
+	pods, err := manifests.Decode(strings.NewReader(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: api
+spec:
+  replicas: 2
+  template:
+    metadata:
+      labels:
+        app: api
+    spec:
+      topologySpreadConstraints:
+      - maxSkew: 1
+        topologyKey: topology.kubernetes.io/zone
+        whenUnsatisfiable: DoNotSchedule
+        labelSelector:
+          matchLabels:
+            app: api
+      containers:
+      - name: api
+`))
+
+	assert.NoError(t, err)
+	assert.Len(t, pods, 2)
+
+	for _, pod := range pods {
+		assert.Equal(t, map[string]string{"app": "api"}, pod.Labels)
+
+		constraints, err := topologyspread.Get(pod)
+		assert.NoError(t, err)
+		assert.Equal(t, []topologyspread.Constraint{{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: "DoNotSchedule",
+			LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+		}}, constraints)
+	}
+}
+
+func TestDecodeDaemonSet(t *testing.T) {
+	pods, err := manifests.Decode(strings.NewReader(`
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: fluentd
+spec:
+  template:
+    spec:
+      containers:
+      - name: fluentd
+`))
+
+	assert.NoError(t, err)
+	assert.Len(t, pods, 1)
+	assert.Equal(t, "DaemonSet", pods[0].OwnerReferences[0].Kind)
+}
+
+func TestDecodeList(t *testing.T) {
+	pods, err := manifests.Decode(strings.NewReader(`{
+		"apiVersion": "v1",
+		"kind": "List",
+		"items": [
+			{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "a"}, "spec": {"containers": [{"name": "a"}]}},
+			{"apiVersion": "apps/v1", "kind": "ReplicaSet", "metadata": {"name": "b"}, "spec": {"replicas": 2}}
+		]
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b-0", "b-1"}, podNames(pods))
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := manifests.Decode(strings.NewReader("kind: Deployment\nspec: [\n"))
+
+	assert.Error(t, err)
+}
+
+func podNames(pods []*corev1.Pod) []string {
+	names := []string{}
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,288 @@
+package manifests
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Well-known node labels, including their deprecated beta versions.
+var (
+	instanceTypeLabels = []string{"node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"}
+	regionLabels       = []string{"topology.kubernetes.io/region", "failure-domain.beta.kubernetes.io/region"}
+)
+
+// Node selector keys that pin a pod to a node of the snapshot cluster.
+const (
+	hostnameLabel = "kubernetes.io/hostname"
+	nodeNameField = "metadata.name"
+)
+
+// mirrorPodAnnotation is set by the kubelet on the API server representation of static pods.
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// Snapshot is an offline dump of the pods and nodes of a cluster,
+// e.g the output of `kubectl get pods,nodes -A -o json`.
+type Snapshot struct {
+	// Pods that run in the cluster, ready to be simulated.
+	Pods  []*corev1.Pod
+	Nodes []*corev1.Node
+}
+
+// LoadSnapshot reads a cluster snapshot from a YAML or JSON file.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open snapshot file")
+	}
+	defer f.Close()
+
+	return DecodeSnapshot(f)
+}
+
+// DecodeSnapshot reads a cluster snapshot. Finished pods are ignored, and pods that run on
+// every node (DaemonSet and static pods) are simulated once per node.
+func DecodeSnapshot(reader io.Reader) (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Pods:  []*corev1.Pod{},
+		Nodes: []*corev1.Node{},
+	}
+
+	// Pods that run on every node, by namespace and owner name
+	perNodePods := map[string]bool{}
+
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 4096)
+	for {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode snapshot")
+		}
+
+		objects, err := flattenList(raw)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, object := range objects {
+			m := &manifest{}
+			if err := unmarshal(object, m); err != nil {
+				return nil, err
+			}
+
+			switch m.Kind {
+			case "Node":
+				node := &corev1.Node{}
+				if err := unmarshal(object, node); err != nil {
+					return nil, err
+				}
+
+				snapshot.Nodes = append(snapshot.Nodes, node)
+
+			case "Pod":
+				pod := &corev1.Pod{}
+				if err := unmarshal(object, pod); err != nil {
+					return nil, err
+				}
+
+				if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+					continue
+				}
+
+				unpin(&pod.Spec)
+
+				meta := pod.ObjectMeta
+				owner := perNodeOwner(pod)
+				if owner != nil {
+					key := pod.Namespace + "/" + owner.Name
+					if perNodePods[key] {
+						continue
+					}
+
+					perNodePods[key] = true
+					meta.Name = owner.Name
+				}
+
+				// Pods keep their names, unless they're simulated like the pods of a DaemonSet
+				var pods []*corev1.Pod
+				if owner != nil {
+					pods, err = replicate(meta, podTemplate(pod), m.Spec.podSpecExtensions, 1, owner)
+				} else {
+					pods, err = single(meta, podTemplate(pod), m.Spec.podSpecExtensions, nil)
+				}
+				if err != nil {
+					return nil, err
+				}
+
+				snapshot.Pods = append(snapshot.Pods, pods...)
+			}
+		}
+	}
+
+	return snapshot, nil
+}
+
+// unpin removes the node selectors and node affinity of a pod that pin it to a node of the
+// snapshot cluster, which doesn't exist in the simulated cluster. Other selectors are kept.
+func unpin(spec *corev1.PodSpec) {
+	delete(spec.NodeSelector, hostnameLabel)
+	if len(spec.NodeSelector) == 0 {
+		spec.NodeSelector = nil
+	}
+
+	if spec.Affinity == nil || spec.Affinity.NodeAffinity == nil {
+		return
+	}
+
+	nodeAffinity := spec.Affinity.NodeAffinity
+	if required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+		terms := []corev1.NodeSelectorTerm{}
+		for _, term := range required.NodeSelectorTerms {
+			if term, ok := unpinTerm(term); ok {
+				terms = append(terms, term)
+			}
+		}
+
+		required.NodeSelectorTerms = terms
+		if len(terms) == 0 {
+			nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = nil
+		}
+	}
+
+	preferred := []corev1.PreferredSchedulingTerm{}
+	for _, term := range nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+		if preference, ok := unpinTerm(term.Preference); ok {
+			term.Preference = preference
+			preferred = append(preferred, term)
+		}
+	}
+	nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = preferred
+
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil && len(preferred) == 0 {
+		spec.Affinity.NodeAffinity = nil
+	}
+}
+
+// unpinTerm removes the hostname and node name requirements of a node selector term, or returns
+// false if no requirements are left, since an empty term matches no nodes.
+func unpinTerm(term corev1.NodeSelectorTerm) (corev1.NodeSelectorTerm, bool) {
+	result := corev1.NodeSelectorTerm{}
+	for _, requirement := range term.MatchExpressions {
+		if requirement.Key != hostnameLabel {
+			result.MatchExpressions = append(result.MatchExpressions, requirement)
+		}
+	}
+
+	for _, requirement := range term.MatchFields {
+		if requirement.Key != nodeNameField {
+			result.MatchFields = append(result.MatchFields, requirement)
+		}
+	}
+
+	return result, len(result.MatchExpressions) > 0 || len(result.MatchFields) > 0
+}
+
+// flattenList returns the items of a List, or the object itself if it's not a List.
+func flattenList(raw json.RawMessage) ([]json.RawMessage, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	m := &manifest{}
+	if err := unmarshal(raw, m); err != nil {
+		return nil, err
+	}
+
+	if m.Kind != "List" {
+		return []json.RawMessage{raw}, nil
+	}
+
+	objects := []json.RawMessage{}
+	for _, item := range m.Items {
+		itemObjects, err := flattenList(item)
+		if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, itemObjects...)
+	}
+
+	return objects, nil
+}
+
+// perNodeOwner returns a DaemonSet owner reference if the pod runs on every node, or nil otherwise.
+// Static pods (e.g kube-proxy) are treated as if they were created by a DaemonSet.
+func perNodeOwner(pod *corev1.Pod) *metav1.OwnerReference {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return &metav1.OwnerReference{
+				APIVersion: "apps/v1",
+				Kind:       "DaemonSet",
+				Name:       owner.Name,
+			}
+		}
+	}
+
+	if _, ok := pod.Annotations[mirrorPodAnnotation]; ok {
+		return &metav1.OwnerReference{
+			APIVersion: "apps/v1",
+			Kind:       "DaemonSet",
+			Name:       strings.TrimSuffix(pod.Name, "-"+pod.Spec.NodeName),
+		}
+	}
+
+	return nil
+}
+
+// InstanceTypes returns the number of nodes of each instance type, sorted by instance type.
+func (s *Snapshot) InstanceTypes() ([]string, map[string]int, error) {
+	counts := map[string]int{}
+	for _, node := range s.Nodes {
+		instanceType := firstLabel(node, instanceTypeLabels)
+		if instanceType == "" {
+			return nil, nil, errors.Errorf("could not find the instance type of node %s", node.Name)
+		}
+
+		counts[instanceType]++
+	}
+
+	instanceTypes := []string{}
+	for instanceType := range counts {
+		instanceTypes = append(instanceTypes, instanceType)
+	}
+	sort.Strings(instanceTypes)
+
+	return instanceTypes, counts, nil
+}
+
+// Region returns the region of the cluster nodes, or an empty string if it's unknown.
+func (s *Snapshot) Region() string {
+	for _, node := range s.Nodes {
+		if region := firstLabel(node, regionLabels); region != "" {
+			return region
+		}
+	}
+
+	return ""
+}
+
+// firstLabel returns the value of the first label the node has, or an empty string.
+func firstLabel(node *corev1.Node, labels []string) string {
+	for _, label := range labels {
+		if value := node.Labels[label]; value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
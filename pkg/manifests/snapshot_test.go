@@ -0,0 +1,126 @@
+package manifests_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/manifests"
+	"github.com/stretchr/testify/assert"
+)
+
+const snapshotJSON = `{
+	"apiVersion": "v1",
+	"kind": "List",
+	"items": [
+		{
+			"apiVersion": "v1", "kind": "Node",
+			"metadata": {"name": "ip-1", "labels": {"node.kubernetes.io/instance-type": "m5.large", "topology.kubernetes.io/region": "eu-west-1"}}
+		},
+		{
+			"apiVersion": "v1", "kind": "Node",
+			"metadata": {"name": "ip-2", "labels": {"beta.kubernetes.io/instance-type": "m5.large"}}
+		},
+		{
+			"apiVersion": "v1", "kind": "Node",
+			"metadata": {"name": "ip-3", "labels": {"node.kubernetes.io/instance-type": "c5.xlarge"}}
+		},
+		{
+			"apiVersion": "v1", "kind": "Pod",
+			"metadata": {"name": "api-7d9f", "namespace": "prod"},
+			"spec": {"nodeName": "ip-1", "containers": [{"name": "api", "resources": {"requests": {"cpu": "1"}}}]},
+			"status": {"phase": "Running"}
+		},
+		{
+			"apiVersion": "v1", "kind": "Pod",
+			"metadata": {"name": "cache-0", "namespace": "prod"},
+			"spec": {
+				"nodeName": "ip-2",
+				"nodeSelector": {"kubernetes.io/hostname": "ip-2", "kubernetes.io/arch": "amd64"},
+				"affinity": {"nodeAffinity": {"requiredDuringSchedulingIgnoredDuringExecution": {"nodeSelectorTerms": [
+					{"matchExpressions": [{"key": "kubernetes.io/hostname", "operator": "In", "values": ["ip-2"]}]},
+					{"matchFields": [{"key": "metadata.name", "operator": "In", "values": ["ip-2"]}],
+					 "matchExpressions": [{"key": "disk", "operator": "In", "values": ["ssd"]}]}
+				]}}},
+				"containers": [{"name": "cache"}]
+			},
+			"status": {"phase": "Running"}
+		},
+		{
+			"apiVersion": "v1", "kind": "Pod",
+			"metadata": {"name": "migrate-x8s", "namespace": "prod"},
+			"spec": {"nodeName": "ip-2", "containers": [{"name": "migrate"}]},
+			"status": {"phase": "Succeeded"}
+		},
+		{
+			"apiVersion": "v1", "kind": "Pod",
+			"metadata": {"name": "aws-node-a", "namespace": "kube-system", "ownerReferences": [{"kind": "DaemonSet", "name": "aws-node"}]},
+			"spec": {"nodeName": "ip-1", "containers": [{"name": "aws-node"}]}
+		},
+		{
+			"apiVersion": "v1", "kind": "Pod",
+			"metadata": {"name": "aws-node-b", "namespace": "kube-system", "ownerReferences": [{"kind": "DaemonSet", "name": "aws-node"}]},
+			"spec": {"nodeName": "ip-2", "containers": [{"name": "aws-node"}]}
+		},
+		{
+			"apiVersion": "v1", "kind": "Pod",
+			"metadata": {"name": "kube-proxy-ip-1", "namespace": "kube-system", "annotations": {"kubernetes.io/config.mirror": "x"}},
+			"spec": {"nodeName": "ip-1", "containers": [{"name": "kube-proxy"}]}
+		},
+		{
+			"apiVersion": "v1", "kind": "Pod",
+			"metadata": {"name": "kube-proxy-ip-2", "namespace": "kube-system", "annotations": {"kubernetes.io/config.mirror": "x"}},
+			"spec": {"nodeName": "ip-2", "containers": [{"name": "kube-proxy"}]}
+		}
+	]
+}`
+
+func TestDecodeSnapshot(t *testing.T) {
+	snapshot, err := manifests.DecodeSnapshot(strings.NewReader(snapshotJSON))
+
+	assert.NoError(t, err)
+	assert.Len(t, snapshot.Nodes, 3)
+	assert.Equal(t, []string{"api-7d9f", "cache-0", "aws-node-0", "kube-proxy-0"}, podNames(snapshot.Pods))
+
+	// Pods are rescheduled by the simulator
+	assert.Empty(t, snapshot.Pods[0].Spec.NodeName)
+	assert.Equal(t, "prod", snapshot.Pods[0].Namespace)
+
+	// Pods that run on every node are simulated as DaemonSet pods
+	assert.Equal(t, "DaemonSet", snapshot.Pods[2].OwnerReferences[0].Kind)
+	assert.Equal(t, "DaemonSet", snapshot.Pods[3].OwnerReferences[0].Kind)
+}
+
+func TestDecodeSnapshotUnpinsPods(t *testing.T) {
+	snapshot, err := manifests.DecodeSnapshot(strings.NewReader(snapshotJSON))
+	assert.NoError(t, err)
+
+	// Pods aren't pinned to the nodes of the snapshot, but keep their other selectors
+	pod := snapshot.Pods[1]
+	assert.Equal(t, map[string]string{"kubernetes.io/arch": "amd64"}, pod.Spec.NodeSelector)
+
+	terms := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	assert.Len(t, terms, 1)
+	assert.Empty(t, terms[0].MatchFields)
+	assert.Equal(t, "disk", terms[0].MatchExpressions[0].Key)
+}
+
+func TestSnapshotInstanceTypes(t *testing.T) {
+	snapshot, err := manifests.DecodeSnapshot(strings.NewReader(snapshotJSON))
+	assert.NoError(t, err)
+
+	instanceTypes, counts, err := snapshot.InstanceTypes()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c5.xlarge", "m5.large"}, instanceTypes)
+	assert.Equal(t, map[string]int{"c5.xlarge": 1, "m5.large": 2}, counts)
+	assert.Equal(t, "eu-west-1", snapshot.Region())
+}
+
+func TestSnapshotInstanceTypesMissingLabel(t *testing.T) {
+	snapshot, err := manifests.DecodeSnapshot(strings.NewReader(`{"apiVersion": "v1", "kind": "Node", "metadata": {"name": "bare"}}`))
+	assert.NoError(t, err)
+
+	_, _, err = snapshot.InstanceTypes()
+
+	assert.EqualError(t, err, "could not find the instance type of node bare")
+}
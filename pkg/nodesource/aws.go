@@ -1,16 +1,14 @@
 package nodesource
 
 import (
-	"bufio"
 	"fmt"
-	"net/http"
-	"strconv"
 	"strings"
 
 	ec2instancesinfo "github.com/cristim/ec2-instances-info"
 	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -22,13 +20,80 @@ type AWSNode struct {
 	GPU           int      `json:"gpu"`
 	MaxPods       int      `json:"maxPods"`
 	Arch          []string `json:"arch"`
-	// TODO: Add VolumeSize ondemand price.
+	Region        string   `json:"region"`
+
+	// SpotPrice is 0 if it's unknown. SpotPercentage is the percentage of nodes of this type that
+	// run on spot.
+	SpotPrice      float64 `json:"spotPriceUSD"`
+	SpotPercentage float64 `json:"spotPercentage"`
+	// The first ReservedNodes nodes of this type cost ReservedPrice, the effective price per hour
+	// of their reservation in the instance data.
+	ReservedNodes int     `json:"reservedNodes"`
+	ReservedPrice float64 `json:"reservedPriceUSD"`
+	// StoragePrice is the price per hour of the root volume.
+	StoragePrice float64 `json:"storagePriceUSD"`
+
+	// Kubelet overrides the resources that are reserved by the kubelet.
+	Kubelet AWSKubelet `json:"kubelet"`
+
+	// Labels and taints of the node group, in addition to the well-known labels.
+	Labels map[string]string `json:"labels,omitempty"`
+	Taints []v1.Taint        `json:"taints,omitempty"`
+}
+
+// AWSNodeGroup is a group of instance types whose nodes have the same labels and taints.
+type AWSNodeGroup struct {
+	// InstanceTypes may have wildcards, e.g r6*.x*large.
+	InstanceTypes          []string `yaml:"instanceTypes"`
+	AWSInstanceTypeFilters `yaml:",inline"`
+
+	Labels map[string]string `yaml:"labels"`
+	Taints []string          `yaml:"taints"`
+	// Volume and Kubelet override the ones of the node source.
+	Volume  *AWSVolume  `yaml:"volume"`
+	Kubelet *AWSKubelet `yaml:"kubelet"`
 }
 
 type AWSNodeSource struct {
-	AWSRegion           string
-	InstanceTypes       []string
-	VolumeSizePerNodeGB int64 // TODO
+	AWSRegion string `yaml:"region"`
+	// InstanceTypes may have wildcards, e.g r6*.x*large.
+	InstanceTypes          []string `yaml:"instanceTypes"`
+	AWSInstanceTypeFilters `yaml:",inline"`
+	// NodeGroups are instance types with labels and taints. Their node types are returned
+	// after the ones of InstanceTypes.
+	NodeGroups []AWSNodeGroup `yaml:"nodeGroups"`
+	Pricing    AWSPricing     `yaml:"pricing"`
+	MaxPods    AWSMaxPods     `yaml:"maxPods"`
+	// Volume is the root volume of every node.
+	Volume AWSVolume `yaml:"volume"`
+	// Kubelet overrides the resources that are reserved by the kubelet of every node.
+	Kubelet AWSKubelet `yaml:"kubelet"`
+}
+
+func init() {
+	Register("aws", newAWSNodeSource)
+}
+
+// newAWSNodeSource creates an AWS node source from the nodes.aws section.
+func newAWSNodeSource(unmarshal func(interface{}) error, options Options) (NodeSource, error) {
+	s := &AWSNodeSource{}
+	if err := unmarshal(s); err != nil {
+		return nil, err
+	}
+
+	if s.AWSRegion == "" {
+		s.AWSRegion = options.Region
+	}
+	if len(s.InstanceTypes) == 0 && len(s.NodeGroups) == 0 && !s.AWSInstanceTypeFilters.isSet() {
+		s.InstanceTypes = options.InstanceTypes
+	}
+
+	// The max pods file may be a URL or a path relative to the config file
+	if s.MaxPods.File != "" && !strings.HasPrefix(s.MaxPods.File, "http://") && !strings.HasPrefix(s.MaxPods.File, "https://") {
+		s.MaxPods.File = options.Path(s.MaxPods.File)
+	}
+
+	return s, nil
 }
 
 type fetchPriceAsyncResult struct {
@@ -36,119 +101,302 @@ type fetchPriceAsyncResult struct {
 	err  error
 }
 
-func (s *AWSNodeSource) GetNodes() ([]*AWSNode, error) {
+func (s *AWSNodeSource) GetNodes() ([]Node, error) {
+	spotPercentage, err := s.Pricing.spotPercentage()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Pricing.Reserved.validate(); err != nil {
+		return nil, err
+	}
+
+	// The reserved prices and ENIs of instance types aren't parsed by ec2instancesinfo
+	var rawInstances []rawInstance
+	if len(s.Pricing.Reserved.Nodes) > 0 || s.MaxPods.needsENIs() {
+		rawInstances, err = getRawInstances()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reservedPrices := map[string]float64{}
+	if len(s.Pricing.Reserved.Nodes) > 0 {
+		reservedPrices, err = s.Pricing.Reserved.reservedPrices(s.AWSRegion, rawInstances)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get reserved prices")
+		}
+	}
+
+	storagePrice, err := s.Volume.hourlyPrice(s.AWSRegion)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid volume")
+	}
+
+	if err := s.Kubelet.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid kubelet")
+	}
+
 	instances, err := ec2instancesinfo.Data()
 	if err != nil {
 		return nil, errors.Wrap(err, "could not get ec2 instances info")
 	}
 
-	maxPodsPerInstance, err := s.getMaxPodsPerInstance()
+	maxPodsPerInstance, err := s.MaxPods.maxPodsPerInstance(instances, rawInstances)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not get max pods per instance")
 	}
 
-	nodes := []*AWSNode{}
+	instanceTypes, err := s.resolveInstanceTypes(s.InstanceTypes, instances, s.AWSRegion, maxPodsPerInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []Node{}
 
-	for _, instanceType := range s.InstanceTypes {
-		// Find max pods for this instance
-		maxPods, ok := maxPodsPerInstance[instanceType]
-		if !ok {
-			return nil, errors.New(fmt.Sprintf("Could not find max pods for instance: %s", instanceType))
+	for _, instanceType := range instanceTypes {
+		node, err := s.getNode(instanceType, instances, maxPodsPerInstance, spotPercentage, reservedPrices)
+		if err != nil {
+			return nil, err
 		}
 
-		// Find info for this instance
-		found := false
-		for _, instance := range *instances {
-			if instanceType == instance.InstanceType {
-				nodes = append(nodes, &AWSNode{
-					InstanceType:  instance.InstanceType,
-					OnDemandPrice: instance.Pricing[s.AWSRegion].Linux.OnDemand,
-					VCPU:          instance.VCPU,
-					Memory:        instance.Memory,
-					GPU:           instance.GPU,
-					Arch:          instance.Arch,
-					MaxPods:       maxPods,
-				})
-
-				found = true
-				break
+		node.StoragePrice = storagePrice
+		node.Kubelet = s.Kubelet
+		nodes = append(nodes, node)
+	}
+
+	for _, group := range s.NodeGroups {
+		taints := []v1.Taint{}
+		for _, taintString := range group.Taints {
+			taint, err := ParseTaint(taintString)
+			if err != nil {
+				return nil, err
+			}
+
+			taints = append(taints, taint)
+		}
+
+		groupStoragePrice := storagePrice
+		if group.Volume != nil {
+			groupStoragePrice, err = group.Volume.hourlyPrice(s.AWSRegion)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid volume of node group")
 			}
 		}
 
-		if !found {
-			return nil, errors.New(fmt.Sprintf("Could not find instance data for %s", instanceType))
+		kubelet := s.Kubelet
+		if group.Kubelet != nil {
+			if err := group.Kubelet.validate(); err != nil {
+				return nil, errors.Wrap(err, "invalid kubelet of node group")
+			}
+
+			kubelet = *group.Kubelet
+		}
+
+		groupInstanceTypes, err := group.resolveInstanceTypes(group.InstanceTypes, instances, s.AWSRegion, maxPodsPerInstance)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid node group")
+		}
+
+		for _, instanceType := range groupInstanceTypes {
+			node, err := s.getNode(instanceType, instances, maxPodsPerInstance, spotPercentage, reservedPrices)
+			if err != nil {
+				return nil, err
+			}
+
+			node.Labels = group.Labels
+			node.Taints = taints
+			node.StoragePrice = groupStoragePrice
+			node.Kubelet = kubelet
+			nodes = append(nodes, node)
 		}
 	}
 
+	if err := validateReservedNodes(nodes); err != nil {
+		return nil, err
+	}
+
 	return nodes, nil
 }
 
-func (s *AWSNodeSource) getMaxPodsPerInstance() (map[string]int, error) {
-	response, err := http.Get("https://raw.githubusercontent.com/awslabs/amazon-eks-ami/master/files/eni-max-pods.txt")
-	if err != nil {
-		return nil, errors.Wrap(err, "could not fetch max pods list")
+// validateReservedNodes returns an error if a reserved instance type has several node types, since
+// a cluster with both of them would get the reservation twice.
+func validateReservedNodes(nodes []Node) error {
+	reservedNodeTypes := map[string]bool{}
+	for _, node := range nodes {
+		if node.GetPricing().ReservedNodes == 0 {
+			continue
+		}
+
+		if reservedNodeTypes[node.GetName()] {
+			return errors.Errorf("reserved instance type %s can't be in more than one node group, "+
+				"its nodes are reserved once per cluster", node.GetName())
+		}
+		reservedNodeTypes[node.GetName()] = true
 	}
-	defer response.Body.Close()
 
-	maxPodsPerInstance := make(map[string]int)
+	return nil
+}
+
+// GetBaselineNodes returns the node types of instance types, without node groups.
+func (s *AWSNodeSource) GetBaselineNodes(instanceTypes []string) ([]Node, error) {
+	baseline := *s
+	baseline.InstanceTypes = instanceTypes
+	baseline.AWSInstanceTypeFilters = AWSInstanceTypeFilters{}
+	baseline.NodeGroups = nil
+
+	return baseline.GetNodes()
+}
+
+// getNode returns the node type of an instance type.
+func (s *AWSNodeSource) getNode(instanceType string, instances *ec2instancesinfo.InstanceData,
+	maxPodsPerInstance map[string]int, spotPercentage float64, reservedPrices map[string]float64) (*AWSNode, error) {
 
-	// Check status code
-	if response.StatusCode != http.StatusOK {
-		return nil, errors.Wrapf(err, "fetch max pods list did not return 200 (%d instead)", response.StatusCode)
+	// Find max pods for this instance
+	maxPods, ok := maxPodsPerInstance[instanceType]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Could not find max pods for instance: %s", instanceType))
 	}
 
-	// Read and parse response
-	scanner := bufio.NewScanner(response.Body)
-	for scanner.Scan() {
-		line := scanner.Text()
+	// Find info for this instance
+	for _, instance := range *instances {
+		if instanceType == instance.InstanceType {
+			onDemandPrice := instance.Pricing[s.AWSRegion].Linux.OnDemand
+			spotPrice := s.Pricing.spotPrice(instanceType, onDemandPrice)
+			if spotPercentage > 0 && spotPrice == 0 {
+				return nil, errors.Errorf("unknown spot price of %s, which isn't included in the instance data, "+
+					"please set it in spotPrices or set spotDiscount", instanceType)
+			}
 
-		// Skip comments
-		if strings.HasPrefix(line, "#") {
-			continue
-		}
+			reservedNodes := s.Pricing.Reserved.Nodes[instanceType]
+			reservedPrice, ok := reservedPrices[instanceType]
+			if reservedNodes > 0 && !ok {
+				return nil, errors.Errorf("unknown reserved price of %s in %s", instanceType, s.AWSRegion)
+			}
 
-		// Parse line
-		splitted := strings.Split(line, " ")
-		if len(splitted) != 2 {
-			return nil, errors.Errorf("could not parse eni-max-pods.txt file, bad line: %s", line)
+			return &AWSNode{
+				InstanceType:   instance.InstanceType,
+				OnDemandPrice:  onDemandPrice,
+				SpotPrice:      spotPrice,
+				SpotPercentage: spotPercentage,
+				ReservedNodes:  reservedNodes,
+				ReservedPrice:  reservedPrice,
+				VCPU:           instance.VCPU,
+				Memory:         instance.Memory,
+				GPU:            instance.GPU,
+				Arch:           instance.Arch,
+				Region:         s.AWSRegion,
+				MaxPods:        maxPods,
+			}, nil
 		}
+	}
 
-		instanceType := splitted[0]
-		maxPods, err := strconv.ParseInt(splitted[1], 10, 32)
-		if err != nil {
-			return nil, errors.Errorf("could not parse eni-max-pods.txt file, bad line: %s", line)
-		}
+	return nil, errors.New(fmt.Sprintf("Could not find instance data for %s", instanceType))
+}
 
-		maxPodsPerInstance[instanceType] = int(maxPods)
-	}
+func (n *AWSNode) GetName() string {
+	return n.InstanceType
+}
 
-	return maxPodsPerInstance, nil
+func (n *AWSNode) GetCapacity() Capacity {
+	return Capacity{VCPU: n.VCPU, MemoryGiB: n.Memory, GPU: n.GPU, MaxPods: n.MaxPods}
+}
+
+func (n *AWSNode) GetPricing() Pricing {
+	return Pricing{
+		OnDemand:      n.OnDemandPrice,
+		Spot:          n.SpotPrice,
+		Storage:       n.StoragePrice,
+		ReservedNodes: n.ReservedNodes,
+	}
 }
 
 func (n *AWSNode) GetHourlyPrice() float64 {
-	// TODO: Add storage price
-	return n.OnDemandPrice
+	return n.getInstanceHourlyPrice() + n.StoragePrice
+}
+
+// GetGroupHourlyPrice returns the price per hour of a number of nodes of this type, the first
+// ReservedNodes of which are reserved. Reservations don't include storage.
+func (n *AWSNode) GetGroupHourlyPrice(nodeCount int) float64 {
+	reservedNodes := nodeCount
+	if reservedNodes > n.ReservedNodes {
+		reservedNodes = n.ReservedNodes
+	}
+
+	return float64(reservedNodes)*n.ReservedPrice + float64(nodeCount-reservedNodes)*n.getInstanceHourlyPrice() +
+		float64(nodeCount)*n.StoragePrice
+}
+
+// getInstanceHourlyPrice returns the price per hour of the instance of a node, by the pricing model.
+func (n *AWSNode) getInstanceHourlyPrice() float64 {
+	return n.OnDemandPrice*(1-n.SpotPercentage/100) + n.SpotPrice*n.SpotPercentage/100
 }
 
 func (n *AWSNode) GetNodeConfig(nodeName string) *config.NodeConfig {
+	labels := n.GetLabels()
+	labels["kubernetes.io/hostname"] = nodeName
+
+	cpu, memory := n.Kubelet.allocatable(
+		*resource.NewMilliQuantity(int64(n.VCPU)*1000, resource.DecimalSI),
+		*resource.NewQuantity(int64(float64(n.Memory)*1024*1024*1024), resource.BinarySI),
+		n.MaxPods,
+	)
+
+	// Nodes whose reserved resources are larger than their capacity can't run pods
+	if cpu.Sign() < 0 {
+		cpu = resource.Quantity{}
+	}
+	if memory.Sign() < 0 {
+		memory = resource.Quantity{}
+	}
+
 	return &config.NodeConfig{
 		Metadata: metav1.ObjectMeta{
-			Name: nodeName,
-			Labels: map[string]string{
-				"beta.kubernetes.io/os": "simulated",
-			},
+			Name:   nodeName,
+			Labels: labels,
 		},
 		Spec: v1.NodeSpec{
 			Unschedulable: false,
+			Taints:        n.Taints,
 		},
 		Status: config.NodeStatus{
 			Allocatable: map[v1.ResourceName]string{
-				// We always assume free 10% vCPU and memory
-				"cpu":            fmt.Sprintf("%dm", int(float32(n.VCPU)*1000*0.9)),
-				"memory":         fmt.Sprintf("%dM", int(float64(n.Memory)*1024*0.9)),
+				"cpu":            fmt.Sprintf("%dm", cpu.MilliValue()),
+				"memory":         fmt.Sprintf("%dKi", memory.Value()/1024),
 				"nvidia.com/gpu": fmt.Sprintf("%d", n.GPU),
 				"pods":           fmt.Sprintf("%d", n.MaxPods),
 			},
 		},
 	}
 }
+
+// GetLabels returns the well-known labels of the node, followed by the labels of its node group.
+// The zone label is set by the simulator, which spreads nodes across zones.
+func (n *AWSNode) GetLabels() map[string]string {
+	arch := "amd64"
+	for _, instanceArch := range n.Arch {
+		if instanceArch == "arm64" {
+			arch = "arm64"
+		}
+	}
+
+	labels := map[string]string{
+		"kubernetes.io/os":                 "linux",
+		"beta.kubernetes.io/os":            "linux",
+		"kubernetes.io/arch":               arch,
+		"beta.kubernetes.io/arch":          arch,
+		"node.kubernetes.io/instance-type": n.InstanceType,
+		"beta.kubernetes.io/instance-type": n.InstanceType,
+	}
+
+	if n.Region != "" {
+		labels["topology.kubernetes.io/region"] = n.Region
+		labels["failure-domain.beta.kubernetes.io/region"] = n.Region
+	}
+
+	for key, value := range n.Labels {
+		labels[key] = value
+	}
+
+	return labels
+}
@@ -0,0 +1,380 @@
+package nodesource
+
+import (
+	"fmt"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Pricing models of Azure nodes.
+const (
+	AzurePricingPayAsYouGo = "pay-as-you-go"
+	AzurePricingSpot       = "spot"
+	AzurePricingReserved1Y = "reserved-1y"
+	AzurePricingReserved3Y = "reserved-3y"
+)
+
+// Network plugins of AKS clusters.
+const (
+	AzureNetworkCNI     = "azure"
+	AzureNetworkOverlay = "overlay"
+	AzureNetworkKubenet = "kubenet"
+)
+
+// aksMinMaxPods is the lowest max pods per node that AKS allows.
+const aksMinMaxPods = 10
+
+// aksMaxPods are the default and the highest max pods per node of network plugins.
+var aksMaxPods = map[string]struct {
+	defaultValue int
+	limit        int
+}{
+	AzureNetworkCNI:     {30, 250},
+	AzureNetworkOverlay: {250, 250},
+	AzureNetworkKubenet: {110, 110},
+}
+
+// aksDefaultNodePool is the name of the node pool of VM sizes without a node pool.
+const aksDefaultNodePool = "nodepool1"
+
+// aksReservedCPU is the CPU that AKS reserves for the kubelet in millicores, by vCPUs.
+var aksReservedCPU = []struct {
+	vcpu   int
+	millis int64
+}{
+	{1, 60},
+	{2, 100},
+	{4, 140},
+	{8, 180},
+	{16, 260},
+	{32, 420},
+	{64, 740},
+}
+
+// AKS hard eviction thresholds of the available memory, in bytes.
+const (
+	aksEvictionHard       = 100 * 1024 * 1024
+	aksLegacyEvictionHard = 750 * 1024 * 1024
+)
+
+// AzureNode is an Azure VM size of an AKS node pool.
+type AzureNode struct {
+	VMSize        string  `json:"vmSize"`
+	Series        string  `json:"series"`
+	OnDemandPrice float64 `json:"payAsYouGoPriceUSD"`
+	SpotPrice     float64 `json:"spotPriceUSD"`
+	// Price is the price per hour by the pricing model.
+	Price        float64 `json:"priceUSD"`
+	VCPU         int     `json:"vcpu"`
+	MemoryGiB    float32 `json:"memoryGiB"`
+	GPU          int     `json:"gpu"`
+	MaxDataDisks int     `json:"maxDataDisks"`
+	MaxPods      int     `json:"maxPods"`
+	Arch         string  `json:"arch"`
+	Region       string  `json:"region"`
+	Spot         bool    `json:"spot"`
+	// LegacyReservations reserves resources like AKS clusters older than 1.29.
+	LegacyReservations bool `json:"legacyReservations"`
+
+	// NodePool, labels and taints of the node pool, in addition to the well-known labels.
+	NodePool string            `json:"nodePool"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Taints   []v1.Taint        `json:"taints,omitempty"`
+}
+
+// AzureNodePool is a group of VM sizes whose nodes have the same labels and taints.
+type AzureNodePool struct {
+	Name    string            `yaml:"name"`
+	VMSizes []string          `yaml:"vmSizes"`
+	Labels  map[string]string `yaml:"labels"`
+	Taints  []string          `yaml:"taints"`
+	// MaxPods overrides the max pods per node of the node source.
+	MaxPods int `yaml:"maxPods"`
+}
+
+// AzurePricing is how Azure nodes are paid for.
+type AzurePricing struct {
+	// Model is pay-as-you-go (default), spot, reserved-1y or reserved-3y.
+	Model string `yaml:"model"`
+	// Prices override the pay-as-you-go prices per hour of VM sizes, in USD.
+	Prices map[string]float64 `yaml:"prices"`
+	// TaintSpot taints spot nodes like AKS, so that only pods that tolerate the taint run on them.
+	TaintSpot bool `yaml:"taintSpot"`
+}
+
+type AzureNodeSource struct {
+	Region  string   `yaml:"region"`
+	VMSizes []string `yaml:"vmSizes"`
+	// NodePools are VM sizes with labels and taints. Their node types are returned after the
+	// ones of VMSizes.
+	NodePools []AzureNodePool `yaml:"nodePools"`
+	Pricing   AzurePricing    `yaml:"pricing"`
+	// Network is the network plugin: azure (default), overlay or kubenet.
+	Network string `yaml:"network"`
+	// MaxPods is the max pods of every node (default: by the network plugin).
+	MaxPods int `yaml:"maxPods"`
+	// LegacyReservations reserves resources like AKS clusters older than 1.29.
+	LegacyReservations bool `yaml:"legacyReservations"`
+}
+
+func init() {
+	Register("azure", newAzureNodeSource)
+}
+
+// newAzureNodeSource creates an Azure node source from the nodes.azure section.
+func newAzureNodeSource(unmarshal func(interface{}) error, options Options) (NodeSource, error) {
+	s := &AzureNodeSource{}
+	if err := unmarshal(s); err != nil {
+		return nil, err
+	}
+
+	if s.Region == "" {
+		s.Region = options.Region
+	}
+	if len(s.VMSizes) == 0 && len(s.NodePools) == 0 {
+		s.VMSizes = options.InstanceTypes
+	}
+
+	return s, nil
+}
+
+func (s *AzureNodeSource) GetNodes() ([]Node, error) {
+	regionMultiplier, ok := azureRegionMultipliers[s.Region]
+	if !ok {
+		return nil, errors.Errorf("unknown Azure region %s", s.Region)
+	}
+
+	switch s.Pricing.Model {
+	case "", AzurePricingPayAsYouGo, AzurePricingSpot, AzurePricingReserved1Y, AzurePricingReserved3Y:
+	default:
+		return nil, errors.Errorf("unknown pricing model %s, expected pay-as-you-go, spot, reserved-1y or reserved-3y", s.Pricing.Model)
+	}
+
+	network := s.Network
+	if network == "" {
+		network = AzureNetworkCNI
+	}
+
+	networkMaxPods, ok := aksMaxPods[network]
+	if !ok {
+		return nil, errors.Errorf("unknown network plugin %s, expected azure, overlay or kubenet", s.Network)
+	}
+
+	nodePools := []AzureNodePool{{Name: aksDefaultNodePool, VMSizes: s.VMSizes}}
+	nodePools = append(nodePools, s.NodePools...)
+
+	nodes := []Node{}
+	for _, nodePool := range nodePools {
+		taints := []v1.Taint{}
+		for _, taintString := range nodePool.Taints {
+			taint, err := ParseTaint(taintString)
+			if err != nil {
+				return nil, err
+			}
+
+			taints = append(taints, taint)
+		}
+
+		// AKS taints spot nodes, so that only pods that tolerate evictions run on them
+		if s.Pricing.Model == AzurePricingSpot && s.Pricing.TaintSpot {
+			taints = append(taints, v1.Taint{
+				Key:    "kubernetes.azure.com/scalesetpriority",
+				Value:  "spot",
+				Effect: v1.TaintEffectNoSchedule,
+			})
+		}
+
+		maxPods := nodePool.MaxPods
+		if maxPods == 0 {
+			maxPods = s.MaxPods
+		}
+		if maxPods == 0 {
+			maxPods = networkMaxPods.defaultValue
+		}
+		if maxPods < aksMinMaxPods || maxPods > networkMaxPods.limit {
+			return nil, errors.Errorf("max pods of the %s network plugin must be between %d and %d, got %d",
+				network, aksMinMaxPods, networkMaxPods.limit, maxPods)
+		}
+
+		for _, name := range nodePool.VMSizes {
+			node, err := s.getNode(name, regionMultiplier)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid node pool %s", nodePool.Name)
+			}
+
+			node.MaxPods = maxPods
+			node.NodePool = nodePool.Name
+			node.Labels = nodePool.Labels
+			node.Taints = taints
+
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes, nil
+}
+
+// GetBaselineNodes returns the node types of VM sizes, without node pools.
+func (s *AzureNodeSource) GetBaselineNodes(vmSizes []string) ([]Node, error) {
+	baseline := *s
+	baseline.VMSizes = vmSizes
+	baseline.NodePools = nil
+
+	return baseline.GetNodes()
+}
+
+// getNode returns the node type of a VM size.
+func (s *AzureNodeSource) getNode(name string, regionMultiplier float64) (*AzureNode, error) {
+	vmSize, err := getAzureVMSize(name)
+	if err != nil {
+		return nil, err
+	}
+
+	onDemandPrice := vmSize.Price * regionMultiplier
+	if price, ok := s.Pricing.Prices[name]; ok {
+		onDemandPrice = price
+	}
+
+	discounts := azureSeriesDiscounts[vmSize.Series]
+	node := &AzureNode{
+		VMSize:             name,
+		Series:             vmSize.Series,
+		OnDemandPrice:      onDemandPrice,
+		SpotPrice:          onDemandPrice * discounts.Spot,
+		VCPU:               vmSize.VCPU,
+		MemoryGiB:          vmSize.MemoryGiB,
+		GPU:                vmSize.GPU,
+		MaxDataDisks:       vmSize.MaxDataDisks,
+		Arch:               "amd64",
+		Region:             s.Region,
+		Spot:               s.Pricing.Model == AzurePricingSpot,
+		LegacyReservations: s.LegacyReservations,
+	}
+
+	if azureArm64Series[vmSize.Series] {
+		node.Arch = "arm64"
+	}
+
+	switch s.Pricing.Model {
+	case "", AzurePricingPayAsYouGo:
+		node.Price = node.OnDemandPrice
+	case AzurePricingSpot:
+		node.Price = node.SpotPrice
+	case AzurePricingReserved1Y:
+		node.Price = onDemandPrice * discounts.Reserved1Year
+	case AzurePricingReserved3Y:
+		node.Price = onDemandPrice * discounts.Reserved3Years
+	}
+
+	return node, nil
+}
+
+func (n *AzureNode) GetName() string {
+	return n.VMSize
+}
+
+func (n *AzureNode) GetCapacity() Capacity {
+	return Capacity{VCPU: n.VCPU, MemoryGiB: n.MemoryGiB, GPU: n.GPU, MaxPods: n.MaxPods}
+}
+
+func (n *AzureNode) GetPricing() Pricing {
+	return Pricing{OnDemand: n.OnDemandPrice, Spot: n.SpotPrice}
+}
+
+func (n *AzureNode) GetHourlyPrice() float64 {
+	return n.Price
+}
+
+func (n *AzureNode) GetGroupHourlyPrice(nodeCount int) float64 {
+	return float64(nodeCount) * n.Price
+}
+
+// GetNodeConfig returns a node with the allocatable resources of AKS nodes. The kubelet reserves
+// CPU by aksReservedCPU, and memory for pods (20Mi per pod and 50Mi, up to 25% of the memory)
+// with 100Mi for hard eviction, or like GKE with 750Mi for hard eviction on legacy clusters.
+func (n *AzureNode) GetNodeConfig(nodeName string) *config.NodeConfig {
+	labels := n.GetLabels()
+	labels["kubernetes.io/hostname"] = nodeName
+
+	cpuMillis := int64(n.VCPU) * 1000
+	for _, reservation := range aksReservedCPU {
+		if n.VCPU < reservation.vcpu {
+			break
+		}
+
+		cpuMillis = int64(n.VCPU)*1000 - reservation.millis
+	}
+
+	memoryBytes := int64(float64(n.MemoryGiB) * 1024 * 1024 * 1024)
+	if n.LegacyReservations {
+		memoryBytes -= gkeReservedMemory(float64(n.MemoryGiB)) + aksLegacyEvictionHard
+	} else {
+		reserved := int64(20*n.MaxPods+50) * 1024 * 1024
+		if reserved > memoryBytes/4 {
+			reserved = memoryBytes / 4
+		}
+
+		memoryBytes -= reserved + aksEvictionHard
+	}
+
+	if memoryBytes < 0 {
+		memoryBytes = 0
+	}
+
+	return &config.NodeConfig{
+		Metadata: metav1.ObjectMeta{
+			Name:   nodeName,
+			Labels: labels,
+		},
+		Spec: v1.NodeSpec{
+			Unschedulable: false,
+			Taints:        n.Taints,
+		},
+		Status: config.NodeStatus{
+			Allocatable: map[v1.ResourceName]string{
+				"cpu":                           fmt.Sprintf("%dm", cpuMillis),
+				"memory":                        fmt.Sprintf("%dKi", memoryBytes/1024),
+				"nvidia.com/gpu":                fmt.Sprintf("%d", n.GPU),
+				"pods":                          fmt.Sprintf("%d", n.MaxPods),
+				"attachable-volumes-azure-disk": fmt.Sprintf("%d", n.MaxDataDisks),
+			},
+		},
+	}
+}
+
+// GetLabels returns the well-known labels of AKS nodes, followed by the labels of the node pool.
+// The zone label is set by the simulator, which spreads nodes across zones.
+func (n *AzureNode) GetLabels() map[string]string {
+	labels := map[string]string{
+		"kubernetes.io/os":                 "linux",
+		"beta.kubernetes.io/os":            "linux",
+		"kubernetes.io/arch":               n.Arch,
+		"beta.kubernetes.io/arch":          n.Arch,
+		"node.kubernetes.io/instance-type": n.VMSize,
+		"beta.kubernetes.io/instance-type": n.VMSize,
+		"kubernetes.azure.com/agentpool":   n.NodePool,
+		"agentpool":                        n.NodePool,
+	}
+
+	if n.GPU > 0 {
+		labels["kubernetes.azure.com/accelerator"] = "nvidia"
+	}
+
+	if n.Spot {
+		labels["kubernetes.azure.com/scalesetpriority"] = "spot"
+	}
+
+	if n.Region != "" {
+		labels["topology.kubernetes.io/region"] = n.Region
+		labels["failure-domain.beta.kubernetes.io/region"] = n.Region
+	}
+
+	for key, value := range n.Labels {
+		labels[key] = value
+	}
+
+	return labels
+}
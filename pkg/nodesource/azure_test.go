@@ -0,0 +1,72 @@
+package nodesource_test
+
+import (
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAzureSpotTaint(t *testing.T) {
+	tests := []struct {
+		name      string
+		taintSpot bool
+		taints    int
+	}{
+		{name: "untainted by default", taintSpot: false, taints: 0},
+		{name: "tainted like AKS", taintSpot: true, taints: 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, err := getNodes(t, "azure", map[string]interface{}{
+				"region":  "eastus",
+				"vmSizes": []string{"Standard_D4s_v5"},
+				"pricing": map[string]interface{}{"model": "spot", "taintSpot": test.taintSpot},
+			})
+			assert.Nil(t, err)
+			assert.Len(t, nodes, 1)
+
+			node := nodes[0].(*nodesource.AzureNode)
+			assert.Equal(t, "spot", node.GetLabels()["kubernetes.azure.com/scalesetpriority"])
+			assert.Len(t, node.Taints, test.taints)
+		})
+	}
+}
+
+func TestAzureMaxPods(t *testing.T) {
+	tests := []struct {
+		name     string
+		network  string
+		maxPods  int
+		expected int
+		err      string
+	}{
+		{name: "azure default", network: "", expected: 30},
+		{name: "overlay default", network: "overlay", expected: 250},
+		{name: "kubenet default", network: "kubenet", expected: 110},
+		{name: "azure", network: "azure", maxPods: 250, expected: 250},
+		{name: "kubenet", network: "kubenet", maxPods: 50, expected: 50},
+		{name: "kubenet above limit", network: "kubenet", maxPods: 200, err: "max pods of the kubenet network plugin must be between 10 and 110, got 200"},
+		{name: "azure above limit", network: "azure", maxPods: 251, err: "max pods of the azure network plugin must be between 10 and 250, got 251"},
+		{name: "overlay below limit", network: "overlay", maxPods: 5, err: "max pods of the overlay network plugin must be between 10 and 250, got 5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, err := getNodes(t, "azure", map[string]interface{}{
+				"region":  "eastus",
+				"vmSizes": []string{"Standard_D4s_v5"},
+				"network": test.network,
+				"maxPods": test.maxPods,
+			})
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, nodes[0].GetCapacity().MaxPods)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package nodesource
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// azureVMSize is an Azure VM size with its pay-as-you-go Linux price in eastus, in USD per hour.
+type azureVMSize struct {
+	Series       string
+	VCPU         int
+	MemoryGiB    float32
+	GPU          int
+	MaxDataDisks int
+	Price        float64
+}
+
+// azureSeries is a series of VM sizes whose memory and price are proportional to their vCPUs,
+// e.g Standard_D*s_v5.
+type azureSeries struct {
+	// format is the name of the VM sizes, with the number of vCPUs as a verb.
+	format        string
+	memoryPerVCPU float32
+	pricePerVCPU  float64
+	vcpus         []int
+}
+
+// azureSeriesCatalog are the general purpose, compute and memory optimized series.
+var azureSeriesCatalog = map[string]azureSeries{
+	"Dsv3":  {"Standard_D%ds_v3", 4, 0.048, []int{2, 4, 8, 16, 32, 48, 64}},
+	"Dsv4":  {"Standard_D%ds_v4", 4, 0.048, []int{2, 4, 8, 16, 32, 48, 64}},
+	"Dsv5":  {"Standard_D%ds_v5", 4, 0.048, []int{2, 4, 8, 16, 32, 48, 64, 96}},
+	"Dasv5": {"Standard_D%das_v5", 4, 0.043, []int{2, 4, 8, 16, 32, 48, 64, 96}},
+	"Dpsv5": {"Standard_D%dps_v5", 4, 0.0385, []int{2, 4, 8, 16, 32, 48, 64}},
+	"Esv3":  {"Standard_E%ds_v3", 8, 0.063, []int{2, 4, 8, 16, 32, 48}},
+	"Esv5":  {"Standard_E%ds_v5", 8, 0.063, []int{2, 4, 8, 16, 20, 32, 48, 64}},
+	"Easv5": {"Standard_E%das_v5", 8, 0.0565, []int{2, 4, 8, 16, 20, 32, 48, 64}},
+	"Fsv2":  {"Standard_F%ds_v2", 2, 0.0423, []int{2, 4, 8, 16, 32, 48, 64, 72}},
+}
+
+// azureVMSizes are the VM sizes that aren't in a proportional series: burstable and GPU sizes.
+var azureVMSizes = map[string]azureVMSize{
+	"Standard_B2s":  {Series: "Bs", VCPU: 2, MemoryGiB: 4, MaxDataDisks: 4, Price: 0.0416},
+	"Standard_B2ms": {Series: "Bs", VCPU: 2, MemoryGiB: 8, MaxDataDisks: 4, Price: 0.0832},
+	"Standard_B4ms": {Series: "Bs", VCPU: 4, MemoryGiB: 16, MaxDataDisks: 8, Price: 0.166},
+	"Standard_B8ms": {Series: "Bs", VCPU: 8, MemoryGiB: 32, MaxDataDisks: 16, Price: 0.333},
+
+	"Standard_NC6s_v3":         {Series: "NCsv3", VCPU: 6, MemoryGiB: 112, GPU: 1, MaxDataDisks: 12, Price: 3.06},
+	"Standard_NC12s_v3":        {Series: "NCsv3", VCPU: 12, MemoryGiB: 224, GPU: 2, MaxDataDisks: 24, Price: 6.12},
+	"Standard_NC24s_v3":        {Series: "NCsv3", VCPU: 24, MemoryGiB: 448, GPU: 4, MaxDataDisks: 32, Price: 12.24},
+	"Standard_NC4as_T4_v3":     {Series: "NCasT4v3", VCPU: 4, MemoryGiB: 28, GPU: 1, MaxDataDisks: 8, Price: 0.526},
+	"Standard_NC8as_T4_v3":     {Series: "NCasT4v3", VCPU: 8, MemoryGiB: 56, GPU: 1, MaxDataDisks: 16, Price: 0.752},
+	"Standard_NC16as_T4_v3":    {Series: "NCasT4v3", VCPU: 16, MemoryGiB: 110, GPU: 1, MaxDataDisks: 32, Price: 1.204},
+	"Standard_NC64as_T4_v3":    {Series: "NCasT4v3", VCPU: 64, MemoryGiB: 440, GPU: 4, MaxDataDisks: 32, Price: 4.352},
+	"Standard_NC24ads_A100_v4": {Series: "NCadsA100v4", VCPU: 24, MemoryGiB: 220, GPU: 1, MaxDataDisks: 8, Price: 3.673},
+	"Standard_NC48ads_A100_v4": {Series: "NCadsA100v4", VCPU: 48, MemoryGiB: 440, GPU: 2, MaxDataDisks: 16, Price: 7.346},
+}
+
+// azureDiscounts are the fractions of the pay-as-you-go price that spot VMs and VMs with 1 and 3
+// year reservations cost, by series.
+type azureDiscounts struct {
+	Spot           float64
+	Reserved1Year  float64
+	Reserved3Years float64
+}
+
+var azureSeriesDiscounts = map[string]azureDiscounts{
+	"Bs":          {0.20, 0.63, 0.40},
+	"Dsv3":        {0.13, 0.63, 0.41},
+	"Dsv4":        {0.13, 0.63, 0.41},
+	"Dsv5":        {0.12, 0.62, 0.40},
+	"Dasv5":       {0.12, 0.62, 0.40},
+	"Dpsv5":       {0.12, 0.62, 0.40},
+	"Esv3":        {0.13, 0.63, 0.41},
+	"Esv5":        {0.12, 0.62, 0.40},
+	"Easv5":       {0.12, 0.62, 0.40},
+	"Fsv2":        {0.13, 0.62, 0.40},
+	"NCsv3":       {0.20, 0.62, 0.36},
+	"NCasT4v3":    {0.20, 0.62, 0.39},
+	"NCadsA100v4": {0.30, 0.62, 0.40},
+}
+
+// azureArm64Series are the series of Ampere Altra VMs.
+var azureArm64Series = map[string]bool{"Dpsv5": true}
+
+// azureRegionMultipliers are the prices of regions relative to eastus.
+var azureRegionMultipliers = map[string]float64{
+	"eastus":             1,
+	"eastus2":            1,
+	"centralus":          1,
+	"southcentralus":     1,
+	"westus2":            1,
+	"westus3":            1,
+	"westus":             1.083,
+	"canadacentral":      1.1,
+	"northeurope":        1.08,
+	"westeurope":         1.12,
+	"uksouth":            1.1,
+	"francecentral":      1.15,
+	"germanywestcentral": 1.15,
+	"swedencentral":      1.08,
+	"southeastasia":      1.15,
+	"japaneast":          1.25,
+	"australiaeast":      1.25,
+	"centralindia":       1.08,
+}
+
+// getAzureVMSize returns a VM size by its name.
+func getAzureVMSize(name string) (azureVMSize, error) {
+	if vmSize, ok := azureVMSizes[name]; ok {
+		return vmSize, nil
+	}
+
+	for seriesName, series := range azureSeriesCatalog {
+		for _, vcpu := range series.vcpus {
+			if fmt.Sprintf(series.format, vcpu) != name {
+				continue
+			}
+
+			// Series VM sizes have 2 data disks per vCPU, up to 32
+			maxDataDisks := 2 * vcpu
+			if maxDataDisks > 32 {
+				maxDataDisks = 32
+			}
+
+			return azureVMSize{
+				Series:       seriesName,
+				VCPU:         vcpu,
+				MemoryGiB:    series.memoryPerVCPU * float32(vcpu),
+				MaxDataDisks: maxDataDisks,
+				Price:        series.pricePerVCPU * float64(vcpu),
+			}, nil
+		}
+	}
+
+	return azureVMSize{}, errors.Errorf("unknown VM size %s", name)
+}
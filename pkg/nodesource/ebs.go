@@ -0,0 +1,133 @@
+package nodesource
+
+import "github.com/pkg/errors"
+
+// Baseline performance of gp3 volumes, which is included in their storage price.
+const (
+	gp3BaselineIOPS       = 3000
+	gp3BaselineThroughput = 125
+)
+
+// AWSVolume is the EBS root volume of every node.
+type AWSVolume struct {
+	// Size is in GB. Nodes don't have a volume if it's 0.
+	Size int64 `yaml:"size"`
+	// Type is gp2, gp3 (default), io1 or io2.
+	Type string `yaml:"type"`
+	// IOPS are the provisioned IOPS of gp3, io1 and io2 volumes.
+	IOPS int64 `yaml:"iops"`
+	// Throughput is the provisioned throughput of gp3 volumes, in MiB/s.
+	Throughput int64 `yaml:"throughput"`
+}
+
+// ebsPrices are the EBS prices of a region, in USD per month.
+type ebsPrices struct {
+	// Storage is the price per GB of every volume type.
+	Storage map[string]float64
+	// GP3IOPS and GP3Throughput are the prices per IOPS and MiB/s above the gp3 baseline.
+	GP3IOPS       float64
+	GP3Throughput float64
+	// ProvisionedIOPS is the price per IOPS of io1 volumes, and of the first 32,000 IOPS of io2 volumes.
+	ProvisionedIOPS float64
+}
+
+// ebsPricesPerRegion are the EBS prices of regions.
+var ebsPricesPerRegion = map[string]ebsPrices{
+	"us-east-1":      {map[string]float64{"gp2": 0.10, "gp3": 0.08, "io1": 0.125, "io2": 0.125}, 0.005, 0.04, 0.065},
+	"us-east-2":      {map[string]float64{"gp2": 0.10, "gp3": 0.08, "io1": 0.125, "io2": 0.125}, 0.005, 0.04, 0.065},
+	"us-west-1":      {map[string]float64{"gp2": 0.12, "gp3": 0.096, "io1": 0.138, "io2": 0.138}, 0.006, 0.048, 0.072},
+	"us-west-2":      {map[string]float64{"gp2": 0.10, "gp3": 0.08, "io1": 0.125, "io2": 0.125}, 0.005, 0.04, 0.065},
+	"ca-central-1":   {map[string]float64{"gp2": 0.11, "gp3": 0.088, "io1": 0.138, "io2": 0.138}, 0.0055, 0.044, 0.072},
+	"eu-west-1":      {map[string]float64{"gp2": 0.11, "gp3": 0.088, "io1": 0.138, "io2": 0.138}, 0.0055, 0.044, 0.072},
+	"eu-west-2":      {map[string]float64{"gp2": 0.116, "gp3": 0.0928, "io1": 0.145, "io2": 0.145}, 0.0058, 0.0464, 0.076},
+	"eu-central-1":   {map[string]float64{"gp2": 0.119, "gp3": 0.0952, "io1": 0.149, "io2": 0.149}, 0.006, 0.048, 0.078},
+	"ap-south-1":     {map[string]float64{"gp2": 0.114, "gp3": 0.0912, "io1": 0.131, "io2": 0.131}, 0.0057, 0.0456, 0.068},
+	"ap-southeast-1": {map[string]float64{"gp2": 0.12, "gp3": 0.096, "io1": 0.138, "io2": 0.138}, 0.006, 0.048, 0.072},
+	"ap-southeast-2": {map[string]float64{"gp2": 0.12, "gp3": 0.096, "io1": 0.138, "io2": 0.138}, 0.006, 0.048, 0.072},
+	"ap-northeast-1": {map[string]float64{"gp2": 0.12, "gp3": 0.096, "io1": 0.142, "io2": 0.142}, 0.006, 0.048, 0.074},
+}
+
+// io2 IOPS above 32,000 and 64,000 are cheaper than the first ones.
+var io2IOPSTiers = []struct {
+	maxIOPS    int64
+	multiplier float64
+}{
+	{32000, 1},
+	{64000, 0.7},
+	{256000, 0.4923},
+}
+
+// hourlyPrice returns the price per hour of the volume in a region.
+func (v *AWSVolume) hourlyPrice(region string) (float64, error) {
+	if v.Size == 0 {
+		return 0, nil
+	}
+	if v.Size < 0 {
+		return 0, errors.Errorf("volume size must be positive, got %d", v.Size)
+	}
+
+	prices, ok := ebsPricesPerRegion[region]
+	if !ok {
+		return 0, errors.Errorf("unknown EBS prices of region %s", region)
+	}
+
+	volumeType := v.Type
+	if volumeType == "" {
+		volumeType = "gp3"
+	}
+
+	storagePrice, ok := prices.Storage[volumeType]
+	if !ok {
+		return 0, errors.Errorf("unknown volume type %s, expected gp2, gp3, io1 or io2", v.Type)
+	}
+
+	if v.Throughput != 0 && volumeType != "gp3" {
+		return 0, errors.Errorf("only gp3 volumes have a provisioned throughput")
+	}
+
+	monthlyPrice := float64(v.Size) * storagePrice
+
+	switch volumeType {
+	case "gp2":
+		if v.IOPS != 0 {
+			return 0, errors.Errorf("gp2 volumes don't have provisioned IOPS")
+		}
+
+	case "gp3":
+		if v.IOPS > gp3BaselineIOPS {
+			monthlyPrice += float64(v.IOPS-gp3BaselineIOPS) * prices.GP3IOPS
+		}
+		if v.Throughput > gp3BaselineThroughput {
+			monthlyPrice += float64(v.Throughput-gp3BaselineThroughput) * prices.GP3Throughput
+		}
+
+	case "io1":
+		if v.IOPS <= 0 {
+			return 0, errors.Errorf("io1 volumes need provisioned IOPS")
+		}
+
+		monthlyPrice += float64(v.IOPS) * prices.ProvisionedIOPS
+
+	case "io2":
+		if v.IOPS <= 0 {
+			return 0, errors.Errorf("io2 volumes need provisioned IOPS")
+		}
+
+		tierStart := int64(0)
+		for _, tier := range io2IOPSTiers {
+			if v.IOPS <= tierStart {
+				break
+			}
+
+			tierIOPS := v.IOPS
+			if tierIOPS > tier.maxIOPS {
+				tierIOPS = tier.maxIOPS
+			}
+
+			monthlyPrice += float64(tierIOPS-tierStart) * prices.ProvisionedIOPS * tier.multiplier
+			tierStart = tier.maxIOPS
+		}
+	}
+
+	return monthlyPrice / HoursPerMonth, nil
+}
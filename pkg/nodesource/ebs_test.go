@@ -0,0 +1,97 @@
+package nodesource_test
+
+import (
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAWSVolumePrices(t *testing.T) {
+	tests := []struct {
+		name   string
+		region string
+		volume map[string]interface{}
+		// monthlyPrice is the price of the volume per month
+		monthlyPrice float64
+		err          string
+	}{
+		{name: "no volume", region: "us-east-1", monthlyPrice: 0},
+		{name: "gp3", region: "us-east-1", volume: map[string]interface{}{"size": 100}, monthlyPrice: 8},
+		{name: "region", region: "eu-west-1", volume: map[string]interface{}{"size": 100, "type": "gp2"}, monthlyPrice: 11},
+		// 1000 IOPS and 125MiB/s above the baseline
+		{
+			name:         "gp3 provisioned performance",
+			region:       "us-east-1",
+			volume:       map[string]interface{}{"size": 100, "iops": 4000, "throughput": 250},
+			monthlyPrice: 8 + 1000*0.005 + 125*0.04,
+		},
+		{name: "io1", region: "us-east-1", volume: map[string]interface{}{"size": 100, "type": "io1", "iops": 1000}, monthlyPrice: 12.5 + 1000*0.065},
+		// IOPS above 32,000 are 30% cheaper
+		{
+			name:         "io2 tiers",
+			region:       "us-east-1",
+			volume:       map[string]interface{}{"size": 100, "type": "io2", "iops": 40000},
+			monthlyPrice: 12.5 + 32000*0.065 + 8000*0.065*0.7,
+		},
+		{
+			name:   "gp2 provisioned IOPS",
+			region: "us-east-1",
+			volume: map[string]interface{}{"size": 100, "type": "gp2", "iops": 1000},
+			err:    "invalid volume: gp2 volumes don't have provisioned IOPS",
+		},
+		{
+			name:   "io1 without IOPS",
+			region: "us-east-1",
+			volume: map[string]interface{}{"size": 100, "type": "io1"},
+			err:    "invalid volume: io1 volumes need provisioned IOPS",
+		},
+		{
+			name:   "unknown type",
+			region: "us-east-1",
+			volume: map[string]interface{}{"size": 100, "type": "st1"},
+			err:    "invalid volume: unknown volume type st1, expected gp2, gp3, io1 or io2",
+		},
+		{
+			name:   "unknown region",
+			region: "sa-east-1",
+			volume: map[string]interface{}{"size": 100},
+			err:    "invalid volume: unknown EBS prices of region sa-east-1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, err := getNodes(t, "aws", map[string]interface{}{
+				"region":        test.region,
+				"instanceTypes": []string{"m5.large"},
+				"volume":        test.volume,
+			})
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.Nil(t, err)
+
+			// Volumes are priced per GB-month, and the price of a node includes its volume
+			pricing := nodes[0].GetPricing()
+			assert.InDelta(t, test.monthlyPrice/nodesource.HoursPerMonth, pricing.Storage, 1e-12)
+			assert.InDelta(t, pricing.OnDemand+pricing.Storage, nodes[0].GetHourlyPrice(), 1e-12)
+		})
+	}
+}
+
+func TestAWSNodeGroupVolume(t *testing.T) {
+	nodes, err := getNodes(t, "aws", map[string]interface{}{
+		"region":        "us-east-1",
+		"instanceTypes": []string{"m5.large"},
+		"volume":        map[string]interface{}{"size": 100},
+		"nodeGroups": []map[string]interface{}{
+			{"instanceTypes": []string{"m5.large"}, "volume": map[string]interface{}{"size": 500}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.InDelta(t, 8.0/nodesource.HoursPerMonth, nodes[0].GetPricing().Storage, 1e-12)
+	assert.InDelta(t, 40.0/nodesource.HoursPerMonth, nodes[1].GetPricing().Storage, 1e-12)
+}
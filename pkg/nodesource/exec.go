@@ -0,0 +1,219 @@
+package nodesource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ExecAPIVersion is the version of the JSON protocol of exec node sources.
+const ExecAPIVersion = "kubesurvival/v1"
+
+// execDefaultTimeout is the timeout of commands without a timeout.
+const execDefaultTimeout = 30 * time.Second
+
+// ExecRequest is the JSON that an exec node source writes to the stdin of its command.
+type ExecRequest struct {
+	APIVersion string `json:"apiVersion"`
+	Region     string `json:"region,omitempty"`
+	// InstanceTypes are the node types of an existing cluster, which should be returned so
+	// that it can be priced.
+	InstanceTypes []string               `json:"instanceTypes,omitempty"`
+	Filters       map[string]interface{} `json:"filters,omitempty"`
+}
+
+// ExecResponse is the JSON that the command of an exec node source writes to its stdout.
+type ExecResponse struct {
+	APIVersion string            `json:"apiVersion"`
+	Nodes      []StaticNodeShape `json:"nodes"`
+	// Error is the reason the command failed, if it did.
+	Error string `json:"error,omitempty"`
+}
+
+// ExecNodeSource runs a command that returns node types, e.g from an internal pricing system.
+// The command reads an ExecRequest from stdin and writes an ExecResponse to stdout.
+type ExecNodeSource struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// Timeout of the command (default: 30s).
+	Timeout time.Duration `yaml:"timeout"`
+	Region  string        `yaml:"region"`
+	// Filters are passed to the command as they are.
+	Filters map[string]interface{} `yaml:"filters"`
+
+	instanceTypes []string
+	nodes         []Node
+}
+
+func init() {
+	Register("exec", newExecNodeSource)
+}
+
+// newExecNodeSource creates an exec node source from the nodes.exec section.
+func newExecNodeSource(unmarshal func(interface{}) error, options Options) (NodeSource, error) {
+	s := &ExecNodeSource{}
+	if err := unmarshal(s); err != nil {
+		return nil, err
+	}
+
+	if s.Command == "" {
+		return nil, errors.New("command is empty")
+	}
+
+	// Commands with a path are relative to the config file, others are looked up in PATH
+	if strings.ContainsAny(s.Command, `/\`) {
+		s.Command = options.Path(s.Command)
+	}
+
+	if s.Region == "" {
+		s.Region = options.Region
+	}
+	s.instanceTypes = options.InstanceTypes
+
+	return s, nil
+}
+
+func (s *ExecNodeSource) GetNodes() ([]Node, error) {
+	if s.nodes != nil {
+		return s.nodes, nil
+	}
+
+	response, err := s.run()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get node types from %s", s.Command)
+	}
+
+	// Node types are in the region of the request, unless the command says otherwise
+	for i := range response.Nodes {
+		if response.Nodes[i].Region == "" {
+			response.Nodes[i].Region = s.Region
+		}
+	}
+
+	s.nodes, err = shapeNodes(response.Nodes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid node types from %s", s.Command)
+	}
+
+	return s.nodes, nil
+}
+
+// GetBaselineNodes returns the node types of the command by their names.
+func (s *ExecNodeSource) GetBaselineNodes(names []string) ([]Node, error) {
+	nodes, err := s.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	return baselineNodes(nodes, names)
+}
+
+// run runs the command with a request, and returns its response.
+func (s *ExecNodeSource) run() (*ExecResponse, error) {
+	request, err := json.Marshal(&ExecRequest{
+		APIVersion:    ExecAPIVersion,
+		Region:        s.Region,
+		InstanceTypes: s.instanceTypes,
+		Filters:       jsonMap(s.Filters),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid filters")
+	}
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = execDefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, s.Command, s.Args...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// The command is killed, but its children may keep its output open, so don't wait for them
+		return nil, errors.Errorf("timed out after %s", timeout)
+	}
+
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, errors.Wrap(errors.New(message), err.Error())
+		}
+
+		return nil, err
+	}
+
+	response := &ExecResponse{}
+	if err := json.Unmarshal(stdout.Bytes(), response); err != nil {
+		return nil, errors.Wrap(err, "invalid response")
+	}
+
+	if response.APIVersion != ExecAPIVersion {
+		return nil, errors.Errorf("unsupported apiVersion %q, expected %s", response.APIVersion, ExecAPIVersion)
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+
+	return response, nil
+}
+
+// jsonMap converts the maps of YAML values, whose keys are interface{}, to maps that can be
+// marshalled to JSON.
+func jsonMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	result := map[string]interface{}{}
+	for key, value := range m {
+		result[key] = jsonValue(value)
+	}
+
+	return result
+}
+
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, v := range value {
+			result[fmt.Sprint(key)] = jsonValue(v)
+		}
+
+		return result
+
+	case []interface{}:
+		result := []interface{}{}
+		for _, v := range value {
+			result = append(result, jsonValue(v))
+		}
+
+		return result
+
+	default:
+		return value
+	}
+}
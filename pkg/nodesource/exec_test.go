@@ -0,0 +1,71 @@
+package nodesource_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/stretchr/testify/assert"
+)
+
+// skipOnWindows skips tests that run testdata/prices.sh, since Windows can't exec shell scripts.
+func skipOnWindows(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts can't be executed on windows")
+	}
+}
+
+func TestExecNodeSource(t *testing.T) {
+	skipOnWindows(t)
+
+	source := newNodeSource(t, "exec", map[string]interface{}{
+		"command": "testdata/prices.sh",
+		"args":    []string{"ok"},
+		"region":  "eu-west-1",
+		"filters": map[string]interface{}{"family": "m5"},
+	})
+
+	nodes, err := source.GetNodes()
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 2)
+
+	assert.Equal(t, "m5-large", nodes[0].GetName())
+	assert.Equal(t, "eu-west-1", nodes[0].GetLabels()["region"])
+	assert.Equal(t, "eu-west-1", nodes[0].GetLabels()["topology.kubernetes.io/region"])
+	assert.Equal(t, nodesource.Capacity{VCPU: 4, MemoryGiB: 16, MaxPods: 110, MaxNodes: 3}, nodes[1].GetCapacity())
+	assert.Equal(t, 0.16, nodes[1].GetHourlyPrice())
+
+	baseline, err := source.(nodesource.BaselineNodeSource).GetBaselineNodes([]string{"m5-xlarge"})
+	assert.Nil(t, err)
+	assert.Equal(t, []nodesource.Node{nodes[1]}, baseline)
+}
+
+func TestExecNodeSourceErrors(t *testing.T) {
+	skipOnWindows(t)
+
+	tests := map[string]string{
+		"error":   "could not get node types from testdata/prices.sh: no prices for region",
+		"version": `could not get node types from testdata/prices.sh: unsupported apiVersion "kubesurvival/v2", expected kubesurvival/v1`,
+		"fail":    "could not get node types from testdata/prices.sh: exit status 1: could not connect to the pricing system",
+	}
+
+	for arg, expected := range tests {
+		source := newNodeSource(t, "exec", map[string]interface{}{"command": "testdata/prices.sh", "args": []string{arg}})
+
+		_, err := source.GetNodes()
+		assert.EqualError(t, err, expected, arg)
+	}
+}
+
+func TestExecNodeSourceTimeout(t *testing.T) {
+	skipOnWindows(t)
+
+	source := newNodeSource(t, "exec", map[string]interface{}{
+		"command": "testdata/prices.sh",
+		"args":    []string{"sleep"},
+		"timeout": "100ms",
+	})
+
+	_, err := source.GetNodes()
+	assert.EqualError(t, err, "could not get node types from testdata/prices.sh: timed out after 100ms")
+}
@@ -0,0 +1,187 @@
+package nodesource
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	ec2instancesinfo "github.com/cristim/ec2-instances-info"
+	"github.com/pkg/errors"
+)
+
+// AWSInstanceTypeFilters select instance types from the instance data, in addition to the ones
+// that are listed by name or by wildcards.
+type AWSInstanceTypeFilters struct {
+	// Families select all instance types of families, e.g m5 or c6g.
+	Families []string `yaml:"families"`
+	MinVCPU  int      `yaml:"minVCPU"`
+	MaxVCPU  int      `yaml:"maxVCPU"`
+	// MinMemoryGiB and MaxMemoryGiB are in GiB.
+	MinMemoryGiB float32 `yaml:"minMemoryGiB"`
+	MaxMemoryGiB float32 `yaml:"maxMemoryGiB"`
+	// Arch is amd64 or arm64.
+	Arch                  string `yaml:"arch"`
+	CurrentGenerationOnly bool   `yaml:"currentGenerationOnly"`
+	// GPU selects only instance types with GPUs if it's true, and only ones without GPUs if it's
+	// false.
+	GPU *bool `yaml:"gpu"`
+	// Exclude are instance types that aren't selected, which may have wildcards.
+	Exclude []string `yaml:"exclude"`
+}
+
+// instanceArchs are the architectures of the instance data by Kubernetes architecture.
+var instanceArchs = map[string]string{"amd64": "x86_64", "arm64": "arm64"}
+
+// isSet checks whether any filter is set.
+func (f *AWSInstanceTypeFilters) isSet() bool {
+	return len(f.Families) > 0 || f.MinVCPU > 0 || f.MaxVCPU > 0 || f.MinMemoryGiB > 0 ||
+		f.MaxMemoryGiB > 0 || f.Arch != "" || f.CurrentGenerationOnly || f.GPU != nil || len(f.Exclude) > 0
+}
+
+// resolveInstanceTypes returns the instance types of names, which may have wildcards like
+// r6*.x*large, and of families that match the filters. If there are no names or families, all
+// instance types that match the filters are returned.
+//
+// Names without wildcards are returned in their order, must be in the instance data and aren't
+// filtered, since they were asked for explicitly. Other instance types are returned in the order
+// of their names, and are skipped if they don't have a price in the region or max pods.
+func (f *AWSInstanceTypeFilters) resolveInstanceTypes(names []string, instances *ec2instancesinfo.InstanceData,
+	region string, maxPodsPerInstance map[string]int) ([]string, error) {
+
+	if !f.isSet() && !hasWildcards(names) {
+		return names, nil
+	}
+
+	arch := ""
+	if f.Arch != "" {
+		var ok bool
+		if arch, ok = instanceArchs[f.Arch]; !ok {
+			return nil, errors.Errorf("unknown arch %s, expected amd64 or arm64", f.Arch)
+		}
+	}
+
+	for _, pattern := range append(append([]string{}, names...), f.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Errorf("invalid instance type pattern %s", pattern)
+		}
+	}
+
+	instanceIndices := map[string]int{}
+	for i, instance := range *instances {
+		instanceIndices[instance.InstanceType] = i
+	}
+
+	selected := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if hasWildcards([]string{name}) {
+			continue
+		}
+		if _, ok := instanceIndices[name]; !ok {
+			return nil, errors.Errorf("Could not find instance data for %s", name)
+		}
+
+		if !seen[name] {
+			seen[name] = true
+			selected = append(selected, name)
+		}
+	}
+
+	matched := []string{}
+	for _, instance := range *instances {
+		instanceType := instance.InstanceType
+		if seen[instanceType] || !f.matchesName(instanceType, names) {
+			continue
+		}
+
+		// Instance types that were selected by wildcards or filters can't always run in the region
+		if instance.Pricing[region].Linux.OnDemand == 0 || maxPodsPerInstance[instanceType] == 0 {
+			continue
+		}
+
+		if !f.matches(instanceType, instance.VCPU, instance.Memory, instance.GPU, instance.Arch, instance.Generation, arch) {
+			continue
+		}
+
+		seen[instanceType] = true
+		matched = append(matched, instanceType)
+	}
+	sort.Strings(matched)
+
+	result := append(selected, matched...)
+	if len(result) == 0 {
+		return nil, errors.New("no instance types match the filters")
+	}
+
+	return result, nil
+}
+
+// matchesName checks whether an instance type matches one of the names with wildcards or the
+// families, or if there are neither, whether it's selected by the filters alone.
+func (f *AWSInstanceTypeFilters) matchesName(instanceType string, names []string) bool {
+	if len(names) == 0 && len(f.Families) == 0 {
+		return true
+	}
+
+	for _, name := range names {
+		if matched, _ := path.Match(name, instanceType); matched {
+			return true
+		}
+	}
+
+	family := strings.SplitN(instanceType, ".", 2)[0]
+	for _, selectedFamily := range f.Families {
+		if family == selectedFamily {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches checks whether an instance type matches the filters.
+func (f *AWSInstanceTypeFilters) matches(instanceType string, vcpu int, memoryGiB float32, gpu int,
+	instanceArchs []string, generation string, arch string) bool {
+
+	for _, pattern := range f.Exclude {
+		if matched, _ := path.Match(pattern, instanceType); matched {
+			return false
+		}
+	}
+
+	if (f.MinVCPU > 0 && vcpu < f.MinVCPU) || (f.MaxVCPU > 0 && vcpu > f.MaxVCPU) {
+		return false
+	}
+	if (f.MinMemoryGiB > 0 && memoryGiB < f.MinMemoryGiB) || (f.MaxMemoryGiB > 0 && memoryGiB > f.MaxMemoryGiB) {
+		return false
+	}
+	if f.GPU != nil && *f.GPU != (gpu > 0) {
+		return false
+	}
+	if f.CurrentGenerationOnly && generation != "current" {
+		return false
+	}
+
+	if arch != "" {
+		for _, instanceArch := range instanceArchs {
+			if instanceArch == arch {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// hasWildcards checks whether any of the names has wildcards.
+func hasWildcards(names []string) bool {
+	for _, name := range names {
+		if strings.ContainsAny(name, "*?[") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package nodesource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceTypeFilters(t *testing.T) {
+	nodes, err := getNodes(t, "aws", map[string]interface{}{
+		"region":                "us-east-1",
+		"instanceTypes":         []string{"m5.large", "r6*.x*large"},
+		"families":              []string{"m6g", "c6g"},
+		"minVCPU":               4,
+		"maxVCPU":               8,
+		"arch":                  "arm64",
+		"currentGenerationOnly": true,
+		"exclude":               []string{"c6g.2*", "r6gd.*"},
+	})
+	assert.Nil(t, err)
+
+	// m5.large is listed by name, so it isn't filtered
+	assert.Equal(t, []string{"m5.large", "c6g.xlarge", "m6g.2xlarge", "m6g.xlarge", "r6g.xlarge"}, nodeNames(nodes))
+}
+
+func TestInstanceTypeFiltersGPU(t *testing.T) {
+	nodes, err := getNodes(t, "aws", map[string]interface{}{
+		"region":   "us-east-1",
+		"families": []string{"g4dn", "m5"},
+		"maxVCPU":  4,
+		"gpu":      true,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"g4dn.xlarge"}, nodeNames(nodes))
+}
+
+func TestInstanceTypeFiltersErrors(t *testing.T) {
+	_, err := getNodes(t, "aws", map[string]interface{}{"region": "us-east-1", "families": []string{"x9z"}})
+	assert.EqualError(t, err, "no instance types match the filters")
+
+	_, err = getNodes(t, "aws", map[string]interface{}{"region": "us-east-1", "instanceTypes": []string{"m5.huge", "m5.*"}})
+	assert.EqualError(t, err, "Could not find instance data for m5.huge")
+
+	_, err = getNodes(t, "aws", map[string]interface{}{"region": "us-east-1", "families": []string{"m5"}, "arch": "s390x"})
+	assert.EqualError(t, err, "unknown arch s390x, expected amd64 or arm64")
+}
@@ -0,0 +1,359 @@
+package nodesource
+
+import (
+	"fmt"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Pricing models of GCP nodes.
+const (
+	GCPPricingOnDemand = "on-demand"
+	GCPPricingSpot     = "spot"
+	GCPPricingCUD1Year = "cud-1y"
+	GCPPricingCUD3Year = "cud-3y"
+)
+
+// gkeDefaultMaxPods is the default max pods per node of GKE clusters.
+const gkeDefaultMaxPods = 110
+
+// gkeDefaultNodePool is the name of the node pool of machine types without a node pool.
+const gkeDefaultNodePool = "default-pool"
+
+// gkeMemoryReservations are the percentages of memory that GKE reserves for the kubelet, by
+// ranges of memory in GiB: 25% of the first 4 GiB, 20% of the next 4 GiB, 10% of the next 8 GiB,
+// 6% of the next 112 GiB and 2% of the rest.
+var gkeMemoryReservations = []struct {
+	startGiB float64
+	endGiB   float64
+	percent  float64
+}{
+	{0, 4, 25},
+	{4, 8, 20},
+	{8, 16, 10},
+	{16, 128, 6},
+	{128, -1, 2},
+}
+
+// GCPNode is a GCE machine type of a GKE node pool.
+type GCPNode struct {
+	MachineType   string  `json:"machineType"`
+	Family        string  `json:"family"`
+	OnDemandPrice float64 `json:"onDemandPriceUSD"`
+	SpotPrice     float64 `json:"spotPriceUSD"`
+	// Price is the price per hour by the pricing model.
+	Price     float64 `json:"priceUSD"`
+	VCPU      int     `json:"vcpu"`
+	MemoryGiB float32 `json:"memoryGiB"`
+	GPU       int     `json:"gpu"`
+	GPUType   string  `json:"gpuType,omitempty"`
+	MaxPods   int     `json:"maxPods"`
+	Region    string  `json:"region"`
+
+	// NodePool, labels and taints of the node pool, in addition to the well-known labels.
+	NodePool string            `json:"nodePool"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Taints   []v1.Taint        `json:"taints,omitempty"`
+}
+
+// GCPNodePool is a group of machine types whose nodes have the same labels, taints and GPUs.
+type GCPNodePool struct {
+	Name         string            `yaml:"name"`
+	MachineTypes []string          `yaml:"machineTypes"`
+	Labels       map[string]string `yaml:"labels"`
+	Taints       []string          `yaml:"taints"`
+	// Accelerator are GPUs that are attached to N1 machine types.
+	Accelerator *GCPAccelerator `yaml:"accelerator"`
+	// MaxPodsPerNode overrides the max pods per node of the node source.
+	MaxPodsPerNode int `yaml:"maxPodsPerNode"`
+}
+
+// GCPAccelerator is GPUs that are attached to every node.
+type GCPAccelerator struct {
+	Type  string `yaml:"type"`
+	Count int    `yaml:"count"`
+}
+
+// GCPPricing is how GCP nodes are paid for.
+type GCPPricing struct {
+	// Model is on-demand (default), spot, cud-1y or cud-3y.
+	Model string `yaml:"model"`
+	// Prices override the on-demand prices per hour of machine types in USD, without attached GPUs.
+	Prices map[string]float64 `yaml:"prices"`
+}
+
+type GCPNodeSource struct {
+	Region       string   `yaml:"region"`
+	MachineTypes []string `yaml:"machineTypes"`
+	// NodePools are machine types with labels, taints and GPUs. Their node types are returned
+	// after the ones of MachineTypes.
+	NodePools []GCPNodePool `yaml:"nodePools"`
+	Pricing   GCPPricing    `yaml:"pricing"`
+	// MaxPodsPerNode is the max pods of every node (default: 110).
+	MaxPodsPerNode int `yaml:"maxPodsPerNode"`
+}
+
+func init() {
+	Register("gcp", newGCPNodeSource)
+}
+
+// newGCPNodeSource creates a GCP node source from the nodes.gcp section.
+func newGCPNodeSource(unmarshal func(interface{}) error, options Options) (NodeSource, error) {
+	s := &GCPNodeSource{}
+	if err := unmarshal(s); err != nil {
+		return nil, err
+	}
+
+	if s.Region == "" {
+		s.Region = options.Region
+	}
+	if len(s.MachineTypes) == 0 && len(s.NodePools) == 0 {
+		s.MachineTypes = options.InstanceTypes
+	}
+
+	return s, nil
+}
+
+func (s *GCPNodeSource) GetNodes() ([]Node, error) {
+	regionMultiplier, ok := gcpRegionMultipliers[s.Region]
+	if !ok {
+		return nil, errors.Errorf("unknown GCP region %s", s.Region)
+	}
+
+	switch s.Pricing.Model {
+	case "", GCPPricingOnDemand, GCPPricingSpot, GCPPricingCUD1Year, GCPPricingCUD3Year:
+	default:
+		return nil, errors.Errorf("unknown pricing model %s, expected on-demand, spot, cud-1y or cud-3y", s.Pricing.Model)
+	}
+
+	nodePools := []GCPNodePool{{Name: gkeDefaultNodePool, MachineTypes: s.MachineTypes}}
+	nodePools = append(nodePools, s.NodePools...)
+
+	nodes := []Node{}
+	for _, nodePool := range nodePools {
+		taints := []v1.Taint{}
+		for _, taintString := range nodePool.Taints {
+			taint, err := ParseTaint(taintString)
+			if err != nil {
+				return nil, err
+			}
+
+			taints = append(taints, taint)
+		}
+
+		maxPods := nodePool.MaxPodsPerNode
+		if maxPods == 0 {
+			maxPods = s.MaxPodsPerNode
+		}
+		if maxPods == 0 {
+			maxPods = gkeDefaultMaxPods
+		}
+		if maxPods < 8 || maxPods > 256 {
+			return nil, errors.Errorf("max pods per node must be between 8 and 256, got %d", maxPods)
+		}
+
+		for _, name := range nodePool.MachineTypes {
+			node, err := s.getNode(name, nodePool.Accelerator, regionMultiplier)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid node pool %s", nodePool.Name)
+			}
+
+			node.MaxPods = maxPods
+			node.NodePool = nodePool.Name
+			node.Labels = nodePool.Labels
+			node.Taints = taints
+
+			// GKE taints nodes with GPUs, so that only pods that request GPUs run on them
+			if node.GPU > 0 {
+				node.Taints = append(append([]v1.Taint{}, taints...), v1.Taint{
+					Key:    "nvidia.com/gpu",
+					Value:  "present",
+					Effect: v1.TaintEffectNoSchedule,
+				})
+			}
+
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes, nil
+}
+
+// GetBaselineNodes returns the node types of machine types, without node pools.
+func (s *GCPNodeSource) GetBaselineNodes(machineTypes []string) ([]Node, error) {
+	baseline := *s
+	baseline.MachineTypes = machineTypes
+	baseline.NodePools = nil
+
+	return baseline.GetNodes()
+}
+
+// getNode returns the node type of a machine type with optional attached GPUs.
+func (s *GCPNodeSource) getNode(name string, accelerator *GCPAccelerator, regionMultiplier float64) (*GCPNode, error) {
+	machineType, err := getGCPMachineType(name)
+	if err != nil {
+		return nil, err
+	}
+
+	onDemandPrice, err := machineType.onDemandPrice()
+	if err != nil {
+		return nil, err
+	}
+	onDemandPrice *= regionMultiplier
+
+	if price, ok := s.Pricing.Prices[name]; ok {
+		onDemandPrice = price
+	}
+
+	if accelerator != nil && accelerator.Count > 0 {
+		if machineType.Family != "n1" {
+			return nil, errors.Errorf("GPUs can only be attached to N1 machine types, not %s", name)
+		}
+		if !gcpN1GPUs[accelerator.Type] {
+			return nil, errors.Errorf("unknown GPU %s", accelerator.Type)
+		}
+
+		machineType.GPU = accelerator.Count
+		machineType.GPUType = accelerator.Type
+		onDemandPrice += float64(accelerator.Count) * gcpGPUPrices[accelerator.Type] * regionMultiplier
+	}
+
+	prices := gcpPrices[machineType.Family]
+	node := &GCPNode{
+		MachineType:   name,
+		Family:        machineType.Family,
+		OnDemandPrice: onDemandPrice,
+		SpotPrice:     onDemandPrice * prices.Spot,
+		VCPU:          machineType.VCPU,
+		MemoryGiB:     machineType.MemoryGiB,
+		GPU:           machineType.GPU,
+		GPUType:       machineType.GPUType,
+		Region:        s.Region,
+	}
+
+	switch s.Pricing.Model {
+	case "", GCPPricingOnDemand:
+		node.Price = node.OnDemandPrice
+	case GCPPricingSpot:
+		node.Price = node.SpotPrice
+	case GCPPricingCUD1Year:
+		node.Price = onDemandPrice * prices.CUD1Year
+	case GCPPricingCUD3Year:
+		node.Price = onDemandPrice * prices.CUD3Years
+	}
+
+	return node, nil
+}
+
+func (n *GCPNode) GetName() string {
+	return n.MachineType
+}
+
+func (n *GCPNode) GetCapacity() Capacity {
+	return Capacity{VCPU: n.VCPU, MemoryGiB: n.MemoryGiB, GPU: n.GPU, MaxPods: n.MaxPods}
+}
+
+func (n *GCPNode) GetPricing() Pricing {
+	return Pricing{OnDemand: n.OnDemandPrice, Spot: n.SpotPrice}
+}
+
+func (n *GCPNode) GetHourlyPrice() float64 {
+	return n.Price
+}
+
+func (n *GCPNode) GetGroupHourlyPrice(nodeCount int) float64 {
+	return float64(nodeCount) * n.Price
+}
+
+// GetNodeConfig returns a node with the allocatable resources of GKE nodes: the kubelet reserves
+// CPU like on EKS, memory by gkeMemoryReservations, and 100Mi for hard eviction.
+func (n *GCPNode) GetNodeConfig(nodeName string) *config.NodeConfig {
+	labels := n.GetLabels()
+	labels["kubernetes.io/hostname"] = nodeName
+
+	cpuMillis := int64(n.VCPU) * 1000
+	cpuMillis -= kubeReservedCPU(cpuMillis)
+
+	memoryBytes := int64(float64(n.MemoryGiB) * 1024 * 1024 * 1024)
+	memoryBytes -= gkeReservedMemory(float64(n.MemoryGiB))
+	memoryBytes -= defaultEvictionHard.Value()
+
+	if memoryBytes < 0 {
+		memoryBytes = 0
+	}
+
+	return &config.NodeConfig{
+		Metadata: metav1.ObjectMeta{
+			Name:   nodeName,
+			Labels: labels,
+		},
+		Spec: v1.NodeSpec{
+			Unschedulable: false,
+			Taints:        n.Taints,
+		},
+		Status: config.NodeStatus{
+			Allocatable: map[v1.ResourceName]string{
+				"cpu":            fmt.Sprintf("%dm", cpuMillis),
+				"memory":         fmt.Sprintf("%dKi", memoryBytes/1024),
+				"nvidia.com/gpu": fmt.Sprintf("%d", n.GPU),
+				"pods":           fmt.Sprintf("%d", n.MaxPods),
+			},
+		},
+	}
+}
+
+// GetLabels returns the well-known labels of GKE nodes, followed by the labels of the node pool.
+// The zone label is set by the simulator, which spreads nodes across zones.
+func (n *GCPNode) GetLabels() map[string]string {
+	labels := map[string]string{
+		"kubernetes.io/os":                 "linux",
+		"beta.kubernetes.io/os":            "linux",
+		"kubernetes.io/arch":               "amd64",
+		"beta.kubernetes.io/arch":          "amd64",
+		"node.kubernetes.io/instance-type": n.MachineType,
+		"beta.kubernetes.io/instance-type": n.MachineType,
+		"cloud.google.com/machine-family":  n.Family,
+		"cloud.google.com/gke-nodepool":    n.NodePool,
+	}
+
+	if n.GPUType != "" {
+		labels["cloud.google.com/gke-accelerator"] = n.GPUType
+	}
+
+	if n.Region != "" {
+		labels["topology.kubernetes.io/region"] = n.Region
+		labels["failure-domain.beta.kubernetes.io/region"] = n.Region
+	}
+
+	for key, value := range n.Labels {
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// gkeReservedMemory returns the memory that GKE reserves for the kubelet, in bytes.
+func gkeReservedMemory(memoryGiB float64) int64 {
+	if memoryGiB < 1 {
+		return 255 * 1024 * 1024
+	}
+
+	reservedGiB := 0.0
+	for _, reservation := range gkeMemoryReservations {
+		if memoryGiB <= reservation.startGiB {
+			break
+		}
+
+		end := memoryGiB
+		if reservation.endGiB != -1 && reservation.endGiB < end {
+			end = reservation.endGiB
+		}
+
+		reservedGiB += (end - reservation.startGiB) * reservation.percent / 100
+	}
+
+	return int64(reservedGiB * 1024 * 1024 * 1024)
+}
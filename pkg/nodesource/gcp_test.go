@@ -0,0 +1,180 @@
+package nodesource_test
+
+import (
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestGCPAllocatable(t *testing.T) {
+	tests := []struct {
+		machineType string
+		cpu         string
+		memory      string
+	}{
+		// 255Mi are reserved on machines with less than 1GiB of memory
+		{machineType: "custom-1-768", cpu: "940m", memory: "422912Ki"},
+		// 25% of the first 4GiB, 20% of the next 4GiB and 10% of the next 8GiB
+		{machineType: "n2-standard-4", cpu: "3920m", memory: "13948518Ki"},
+		// 6% of the next 112GiB and 2% of the rest
+		{machineType: "n2-highmem-32", cpu: "31850m", memory: "255875973Ki"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.machineType, func(t *testing.T) {
+			nodes, err := getNodes(t, "gcp", map[string]interface{}{
+				"region":       "us-central1",
+				"machineTypes": []string{test.machineType},
+			})
+			assert.Nil(t, err)
+
+			allocatable := nodes[0].GetNodeConfig("node-0").Status.Allocatable
+			assert.Equal(t, test.cpu, allocatable["cpu"])
+			assert.Equal(t, test.memory, allocatable["memory"])
+			assert.Equal(t, "110", allocatable["pods"])
+		})
+	}
+}
+
+func TestGCPPricing(t *testing.T) {
+	tests := []struct {
+		name        string
+		region      string
+		machineType string
+		pricing     map[string]interface{}
+		expected    float64
+	}{
+		{name: "on-demand", region: "us-central1", machineType: "n2-standard-4", expected: 0.194236},
+		{name: "region", region: "europe-west1", machineType: "n2-standard-4", expected: 0.194236 * 1.1},
+		{name: "custom", region: "us-central1", machineType: "n2-custom-4-16384", expected: 0.194236 * 1.05},
+		{name: "spot", region: "us-central1", machineType: "n2-standard-4", pricing: map[string]interface{}{"model": "spot"},
+			expected: 0.194236 * 0.25},
+		{name: "cud-1y", region: "us-central1", machineType: "n2-standard-4", pricing: map[string]interface{}{"model": "cud-1y"},
+			expected: 0.194236 * 0.63},
+		{name: "cud-3y", region: "us-central1", machineType: "n2-standard-4", pricing: map[string]interface{}{"model": "cud-3y"},
+			expected: 0.194236 * 0.45},
+		{name: "price override", region: "us-central1", machineType: "n2-standard-4",
+			pricing: map[string]interface{}{"prices": map[string]float64{"n2-standard-4": 0.15}}, expected: 0.15},
+		{name: "machine type with GPUs", region: "us-central1", machineType: "g2-standard-4",
+			expected: 4*0.024988 + 16*0.002927 + 0.5599},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, err := getNodes(t, "gcp", map[string]interface{}{
+				"region":       test.region,
+				"machineTypes": []string{test.machineType},
+				"pricing":      test.pricing,
+			})
+			assert.Nil(t, err)
+
+			assert.InDelta(t, test.expected, nodes[0].GetHourlyPrice(), 1e-9)
+			assert.InDelta(t, 2*test.expected, nodes[0].GetGroupHourlyPrice(2), 1e-9)
+		})
+	}
+}
+
+func TestGCPNodePools(t *testing.T) {
+	nodes, err := getNodes(t, "gcp", map[string]interface{}{
+		"region":       "us-central1",
+		"machineTypes": []string{"e2-standard-4"},
+		"nodePools": []map[string]interface{}{
+			{
+				"name":         "batch",
+				"machineTypes": []string{"n2-highcpu-8"},
+				"labels":       map[string]string{"team": "ml"},
+				"taints":       []string{"dedicated=batch:NoSchedule"},
+			},
+			{
+				"name":         "gpu",
+				"machineTypes": []string{"n1-standard-4"},
+				"accelerator":  map[string]interface{}{"type": "nvidia-tesla-t4", "count": 2},
+				"taints":       []string{"dedicated=ml:NoSchedule"},
+			},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 3)
+
+	// Machine types without a node pool are in the default pool
+	assert.Equal(t, "default-pool", nodes[0].GetLabels()["cloud.google.com/gke-nodepool"])
+	assert.Empty(t, nodes[0].GetNodeConfig("node-0").Spec.Taints)
+
+	labels := nodes[1].GetLabels()
+	assert.Equal(t, "batch", labels["cloud.google.com/gke-nodepool"])
+	assert.Equal(t, "ml", labels["team"])
+	assert.Equal(t, "n2", labels["cloud.google.com/machine-family"])
+	assert.Equal(t, []v1.Taint{{Key: "dedicated", Value: "batch", Effect: v1.TaintEffectNoSchedule}},
+		nodes[1].GetNodeConfig("node-1").Spec.Taints)
+
+	// Nodes with attached GPUs are labeled with the GPU and tainted like on GKE
+	gpuNode := nodes[2]
+	assert.Equal(t, 2, gpuNode.GetCapacity().GPU)
+	assert.Equal(t, "nvidia-tesla-t4", gpuNode.GetLabels()["cloud.google.com/gke-accelerator"])
+	assert.Equal(t, "2", gpuNode.GetNodeConfig("node-2").Status.Allocatable["nvidia.com/gpu"])
+	assert.Equal(t, []v1.Taint{
+		{Key: "dedicated", Value: "ml", Effect: v1.TaintEffectNoSchedule},
+		{Key: "nvidia.com/gpu", Value: "present", Effect: v1.TaintEffectNoSchedule},
+	}, gpuNode.GetNodeConfig("node-2").Spec.Taints)
+	assert.InDelta(t, 4*0.031611+15*0.004237+2*0.35, gpuNode.GetHourlyPrice(), 1e-9)
+}
+
+func TestGCPErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		section map[string]interface{}
+		err     string
+	}{
+		{
+			name:    "unknown region",
+			section: map[string]interface{}{"region": "mars-north1", "machineTypes": []string{"n2-standard-4"}},
+			err:     "unknown GCP region mars-north1",
+		},
+		{
+			name:    "unknown machine type",
+			section: map[string]interface{}{"region": "us-central1", "machineTypes": []string{"n2-standard-3"}},
+			err:     "invalid node pool default-pool: unknown machine type n2-standard-3",
+		},
+		{
+			name:    "custom machine type of a predefined family",
+			section: map[string]interface{}{"region": "us-central1", "machineTypes": []string{"c2-custom-4-16384"}},
+			err:     "invalid node pool default-pool: c2 machine types can't be custom",
+		},
+		{
+			name: "GPUs attached to N2",
+			section: map[string]interface{}{"region": "us-central1", "nodePools": []map[string]interface{}{
+				{"name": "gpu", "machineTypes": []string{"n2-standard-4"}, "accelerator": map[string]interface{}{"type": "nvidia-tesla-t4", "count": 1}},
+			}},
+			err: "invalid node pool gpu: GPUs can only be attached to N1 machine types, not n2-standard-4",
+		},
+		{
+			name: "GPUs that can't be attached",
+			section: map[string]interface{}{"region": "us-central1", "nodePools": []map[string]interface{}{
+				{"name": "gpu", "machineTypes": []string{"n1-standard-4"}, "accelerator": map[string]interface{}{"type": "nvidia-l4", "count": 1}},
+			}},
+			err: "invalid node pool gpu: unknown GPU nvidia-l4",
+		},
+		{
+			name:    "max pods",
+			section: map[string]interface{}{"region": "us-central1", "machineTypes": []string{"n2-standard-4"}, "maxPodsPerNode": 300},
+			err:     "max pods per node must be between 8 and 256, got 300",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := getNodes(t, "gcp", test.section)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestGCPBaselineNodes(t *testing.T) {
+	source := newNodeSource(t, "gcp", map[string]interface{}{"region": "us-central1", "machineTypes": []string{"e2-standard-4"}})
+
+	baseline, err := source.(nodesource.BaselineNodeSource).GetBaselineNodes([]string{"n2-standard-8"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"n2-standard-8"}, nodeNames(baseline))
+}
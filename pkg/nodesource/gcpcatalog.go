@@ -0,0 +1,209 @@
+package nodesource
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// gcpMachineType is a GCE machine type.
+type gcpMachineType struct {
+	Family    string
+	VCPU      int
+	MemoryGiB float32
+	GPU       int
+	GPUType   string
+	Custom    bool
+}
+
+// gcpShape is a predefined shape of machine types of a family, e.g n2-standard-*.
+type gcpShape struct {
+	memoryPerVCPU float32
+	vcpus         []int
+}
+
+// gcpShapes are the predefined machine types of families without GPUs.
+var gcpShapes = map[string]map[string]gcpShape{
+	"e2": {
+		"standard": {4, []int{2, 4, 8, 16, 32}},
+		"highmem":  {8, []int{2, 4, 8, 16}},
+		"highcpu":  {1, []int{2, 4, 8, 16, 32}},
+	},
+	"n1": {
+		"standard": {3.75, []int{1, 2, 4, 8, 16, 32, 64, 96}},
+		"highmem":  {6.5, []int{2, 4, 8, 16, 32, 64, 96}},
+		"highcpu":  {0.9, []int{2, 4, 8, 16, 32, 64, 96}},
+	},
+	"n2": {
+		"standard": {4, []int{2, 4, 8, 16, 32, 48, 64, 80, 96, 128}},
+		"highmem":  {8, []int{2, 4, 8, 16, 32, 48, 64, 80, 96, 128}},
+		"highcpu":  {1, []int{2, 4, 8, 16, 32, 48, 64, 80, 96}},
+	},
+	"n2d": {
+		"standard": {4, []int{2, 4, 8, 16, 32, 48, 64, 80, 96, 128, 224}},
+		"highmem":  {8, []int{2, 4, 8, 16, 32, 48, 64, 80, 96}},
+		"highcpu":  {1, []int{2, 4, 8, 16, 32, 48, 64, 80, 96, 128, 224}},
+	},
+	"c2": {
+		"standard": {4, []int{4, 8, 16, 30, 60}},
+	},
+}
+
+// gcpGPUMachineTypes are the predefined machine types with GPUs.
+var gcpGPUMachineTypes = map[string]gcpMachineType{
+	"a2-highgpu-1g":  {Family: "a2", VCPU: 12, MemoryGiB: 85, GPU: 1, GPUType: "nvidia-tesla-a100"},
+	"a2-highgpu-2g":  {Family: "a2", VCPU: 24, MemoryGiB: 170, GPU: 2, GPUType: "nvidia-tesla-a100"},
+	"a2-highgpu-4g":  {Family: "a2", VCPU: 48, MemoryGiB: 340, GPU: 4, GPUType: "nvidia-tesla-a100"},
+	"a2-highgpu-8g":  {Family: "a2", VCPU: 96, MemoryGiB: 680, GPU: 8, GPUType: "nvidia-tesla-a100"},
+	"a2-megagpu-16g": {Family: "a2", VCPU: 96, MemoryGiB: 1360, GPU: 16, GPUType: "nvidia-tesla-a100"},
+	"g2-standard-4":  {Family: "g2", VCPU: 4, MemoryGiB: 16, GPU: 1, GPUType: "nvidia-l4"},
+	"g2-standard-8":  {Family: "g2", VCPU: 8, MemoryGiB: 32, GPU: 1, GPUType: "nvidia-l4"},
+	"g2-standard-12": {Family: "g2", VCPU: 12, MemoryGiB: 48, GPU: 1, GPUType: "nvidia-l4"},
+	"g2-standard-16": {Family: "g2", VCPU: 16, MemoryGiB: 64, GPU: 1, GPUType: "nvidia-l4"},
+	"g2-standard-24": {Family: "g2", VCPU: 24, MemoryGiB: 96, GPU: 2, GPUType: "nvidia-l4"},
+	"g2-standard-32": {Family: "g2", VCPU: 32, MemoryGiB: 128, GPU: 1, GPUType: "nvidia-l4"},
+	"g2-standard-48": {Family: "g2", VCPU: 48, MemoryGiB: 192, GPU: 4, GPUType: "nvidia-l4"},
+	"g2-standard-96": {Family: "g2", VCPU: 96, MemoryGiB: 384, GPU: 8, GPUType: "nvidia-l4"},
+}
+
+// gcpCustomFamilies are the families with custom machine types.
+var gcpCustomFamilies = map[string]bool{"e2": true, "n1": true, "n2": true, "n2d": true}
+
+// gcpCustomPremium is how much more expensive custom machine types are than predefined ones.
+const gcpCustomPremium = 1.05
+
+// gcpFamilyPrices are the prices of families in us-central1, in USD per hour.
+type gcpFamilyPrices struct {
+	VCPU float64
+	// MemoryGiB is the price of a GiB of memory.
+	MemoryGiB float64
+	// Spot is the fraction of the on-demand price that spot machines cost.
+	Spot float64
+	// CUD1Year and CUD3Years are the fractions of the on-demand price that machines with 1 and 3
+	// year committed use discounts cost.
+	CUD1Year  float64
+	CUD3Years float64
+}
+
+var gcpPrices = map[string]gcpFamilyPrices{
+	"e2":  {0.021811, 0.002923, 0.31, 0.63, 0.45},
+	"n1":  {0.031611, 0.004237, 0.21, 0.63, 0.45},
+	"n2":  {0.031611, 0.004237, 0.25, 0.63, 0.45},
+	"n2d": {0.027502, 0.003686, 0.25, 0.63, 0.45},
+	"c2":  {0.03398, 0.00455, 0.24, 0.63, 0.40},
+	"a2":  {0.031611, 0.004237, 0.30, 0.63, 0.45},
+	"g2":  {0.024988, 0.002927, 0.33, 0.63, 0.45},
+}
+
+// gcpGPUPrices are the prices of GPUs in us-central1, in USD per hour. Their spot and committed
+// use prices are the same fractions as the ones of their machine families.
+var gcpGPUPrices = map[string]float64{
+	"nvidia-tesla-t4":   0.35,
+	"nvidia-tesla-p4":   0.60,
+	"nvidia-tesla-p100": 1.46,
+	"nvidia-tesla-v100": 2.48,
+	"nvidia-tesla-k80":  0.45,
+	"nvidia-tesla-a100": 2.933908,
+	"nvidia-l4":         0.5599,
+}
+
+// gcpN1GPUs are the GPUs that can be attached to N1 machine types.
+var gcpN1GPUs = map[string]bool{
+	"nvidia-tesla-t4":   true,
+	"nvidia-tesla-p4":   true,
+	"nvidia-tesla-p100": true,
+	"nvidia-tesla-v100": true,
+	"nvidia-tesla-k80":  true,
+}
+
+// gcpRegionMultipliers are the prices of regions relative to us-central1.
+var gcpRegionMultipliers = map[string]float64{
+	"us-central1":             1,
+	"us-east1":                1,
+	"us-west1":                1,
+	"us-east4":                1.126,
+	"us-west2":                1.2,
+	"northamerica-northeast1": 1.1,
+	"southamerica-east1":      1.59,
+	"europe-west1":            1.1,
+	"europe-west2":            1.2,
+	"europe-west3":            1.2,
+	"europe-west4":            1.1,
+	"europe-north1":           1.1,
+	"asia-east1":              1.1,
+	"asia-northeast1":         1.29,
+	"asia-southeast1":         1.23,
+	"australia-southeast1":    1.42,
+}
+
+// gcpCustomMachineType matches custom machine types, e.g n2-custom-6-24576 or custom-4-8192 (N1).
+var gcpCustomMachineType = regexp.MustCompile(`^(?:([a-z0-9]+)-)?custom-(\d+)-(\d+)$`)
+
+// getGCPMachineType returns a machine type by its name.
+func getGCPMachineType(name string) (gcpMachineType, error) {
+	if machineType, ok := gcpGPUMachineTypes[name]; ok {
+		return machineType, nil
+	}
+
+	if match := gcpCustomMachineType.FindStringSubmatch(name); match != nil {
+		family := match[1]
+		if family == "" {
+			family = "n1"
+		}
+
+		if !gcpCustomFamilies[family] {
+			return gcpMachineType{}, errors.Errorf("%s machine types can't be custom", family)
+		}
+
+		vcpu, _ := strconv.Atoi(match[2])
+		memoryMiB, _ := strconv.Atoi(match[3])
+		if vcpu == 0 || memoryMiB%256 != 0 {
+			return gcpMachineType{}, errors.Errorf("invalid custom machine type %s, memory must be a multiple of 256 MiB", name)
+		}
+
+		return gcpMachineType{Family: family, VCPU: vcpu, MemoryGiB: float32(memoryMiB) / 1024, Custom: true}, nil
+	}
+
+	parts := strings.Split(name, "-")
+	if len(parts) == 3 {
+		if shape, ok := gcpShapes[parts[0]][parts[1]]; ok {
+			for _, vcpu := range shape.vcpus {
+				if strconv.Itoa(vcpu) == parts[2] {
+					return gcpMachineType{
+						Family:    parts[0],
+						VCPU:      vcpu,
+						MemoryGiB: shape.memoryPerVCPU * float32(vcpu),
+					}, nil
+				}
+			}
+		}
+	}
+
+	return gcpMachineType{}, errors.Errorf("unknown machine type %s", name)
+}
+
+// onDemandPrice returns the price per hour of a machine type with GPUs in us-central1.
+func (m *gcpMachineType) onDemandPrice() (float64, error) {
+	prices, ok := gcpPrices[m.Family]
+	if !ok {
+		return 0, errors.Errorf("unknown prices of %s machine types", m.Family)
+	}
+
+	price := float64(m.VCPU)*prices.VCPU + float64(m.MemoryGiB)*prices.MemoryGiB
+	if m.Custom {
+		price *= gcpCustomPremium
+	}
+
+	if m.GPU > 0 {
+		gpuPrice, ok := gcpGPUPrices[m.GPUType]
+		if !ok {
+			return 0, errors.Errorf("unknown price of GPU %s", m.GPUType)
+		}
+
+		price += float64(m.GPU) * gpuPrice
+	}
+
+	return price, nil
+}
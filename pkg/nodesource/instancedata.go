@@ -0,0 +1,38 @@
+package nodesource
+
+import (
+	"encoding/json"
+
+	"github.com/cristim/ec2-instances-info/data"
+	"github.com/pkg/errors"
+)
+
+// rawInstance is the part of an instance type in the instance data that isn't parsed by
+// ec2instancesinfo.
+type rawInstance struct {
+	InstanceType string `json:"instance_type"`
+	Pricing      map[string]struct {
+		Linux struct {
+			Reserved map[string]string `json:"reserved"`
+		} `json:"linux"`
+	} `json:"pricing"`
+	VPC struct {
+		IPsPerENI int `json:"ips_per_eni"`
+		MaxENIs   int `json:"max_enis"`
+	} `json:"vpc"`
+}
+
+// getRawInstances parses the instance data.
+func getRawInstances() ([]rawInstance, error) {
+	raw, err := data.Asset("data/instances.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read ec2 instances info")
+	}
+
+	instances := []rawInstance{}
+	if err := json.Unmarshal(raw, &instances); err != nil {
+		return nil, errors.Wrap(err, "could not parse ec2 instances info")
+	}
+
+	return instances, nil
+}
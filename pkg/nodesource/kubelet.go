@@ -0,0 +1,145 @@
+package nodesource
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// evictionMemoryAvailable is the eviction signal of the available memory of a node.
+const evictionMemoryAvailable = "memory.available"
+
+// defaultEvictionHard is the hard eviction threshold of the available memory of the kubelet.
+var defaultEvictionHard = resource.MustParse("100Mi")
+
+// cpuReservations are the percentages of CPU that the EKS AMI reserves for the kubelet, by
+// ranges of CPU: 6% of the first core, 1% of the second core, 0.5% of the next 2 cores and 0.25%
+// of the others.
+var cpuReservations = []struct {
+	startMillis int64
+	endMillis   int64
+	// basisPoints are hundredths of a percent
+	basisPoints int64
+}{
+	{0, 1000, 600},
+	{1000, 2000, 100},
+	{2000, 4000, 50},
+	{4000, -1, 25},
+}
+
+// AWSKubelet overrides the resources that the kubelet reserves, which aren't allocatable. By
+// default, kube-reserved is calculated like the EKS AMI does, there's no system-reserved, and
+// the hard eviction threshold of the available memory is 100Mi.
+type AWSKubelet struct {
+	// KubeReserved and SystemReserved are by resource name, e.g cpu: 100m.
+	KubeReserved   map[v1.ResourceName]string `yaml:"kubeReserved" json:"kubeReserved,omitempty"`
+	SystemReserved map[v1.ResourceName]string `yaml:"systemReserved" json:"systemReserved,omitempty"`
+	// EvictionHard are eviction thresholds by signal, e.g memory.available: 5%. Only the available
+	// memory threshold affects the allocatable resources.
+	EvictionHard map[string]string `yaml:"evictionHard" json:"evictionHard,omitempty"`
+}
+
+// validate checks that all reserved resources and thresholds can be parsed.
+func (k *AWSKubelet) validate() error {
+	for name, reservations := range map[string]map[v1.ResourceName]string{
+		"kube-reserved":   k.KubeReserved,
+		"system-reserved": k.SystemReserved,
+	} {
+		for resourceName, value := range reservations {
+			if resourceName != v1.ResourceCPU && resourceName != v1.ResourceMemory {
+				return errors.Errorf("unsupported %s resource %s, expected cpu or memory", name, resourceName)
+			}
+
+			if _, err := resource.ParseQuantity(value); err != nil {
+				return errors.Wrapf(err, "invalid %s %s", name, resourceName)
+			}
+		}
+	}
+
+	for signal, value := range k.EvictionHard {
+		if signal != evictionMemoryAvailable {
+			continue
+		}
+
+		if strings.HasSuffix(value, "%") {
+			percentage, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || percentage < 0 || percentage > 100 {
+				return errors.Errorf("invalid eviction threshold %s: %s", signal, value)
+			}
+		} else if _, err := resource.ParseQuantity(value); err != nil {
+			return errors.Wrapf(err, "invalid eviction threshold %s", signal)
+		}
+	}
+
+	return nil
+}
+
+// allocatable returns the allocatable CPU and memory of a node: its capacity without the
+// kube-reserved and system-reserved resources, and without the hard eviction threshold of memory.
+func (k *AWSKubelet) allocatable(cpuCapacity resource.Quantity, memoryCapacity resource.Quantity,
+	maxPods int) (resource.Quantity, resource.Quantity) {
+
+	kubeReserved := v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(kubeReservedCPU(cpuCapacity.MilliValue()), resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(eksReservedMemory(maxPods), resource.BinarySI),
+	}
+	for name, value := range k.KubeReserved {
+		kubeReserved[name] = resource.MustParse(value)
+	}
+
+	evictionHard := defaultEvictionHard.DeepCopy()
+	if value, ok := k.EvictionHard[evictionMemoryAvailable]; ok {
+		if strings.HasSuffix(value, "%") {
+			percentage, _ := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			evictionHard = *resource.NewQuantity(int64(float64(memoryCapacity.Value())*percentage/100), resource.BinarySI)
+		} else {
+			evictionHard = resource.MustParse(value)
+		}
+	}
+
+	cpu := cpuCapacity.DeepCopy()
+	cpu.Sub(kubeReserved[v1.ResourceCPU])
+
+	memory := memoryCapacity.DeepCopy()
+	memory.Sub(kubeReserved[v1.ResourceMemory])
+	memory.Sub(evictionHard)
+
+	for name, value := range k.SystemReserved {
+		switch name {
+		case v1.ResourceCPU:
+			cpu.Sub(resource.MustParse(value))
+		case v1.ResourceMemory:
+			memory.Sub(resource.MustParse(value))
+		}
+	}
+
+	return cpu, memory
+}
+
+// kubeReservedCPU returns the CPU that the EKS AMI and GKE reserve for the kubelet, in millicores.
+func kubeReservedCPU(cpuMillis int64) int64 {
+	reserved := int64(0)
+	for _, reservation := range cpuReservations {
+		if cpuMillis < reservation.startMillis {
+			break
+		}
+
+		end := cpuMillis
+		if reservation.endMillis != -1 && reservation.endMillis < end {
+			end = reservation.endMillis
+		}
+
+		reserved += (end - reservation.startMillis) * reservation.basisPoints / 100 / 100
+	}
+
+	return reserved
+}
+
+// eksReservedMemory returns the memory that the EKS AMI reserves for the kubelet, in bytes:
+// 255Mi and 11Mi for every pod.
+func eksReservedMemory(maxPods int) int64 {
+	return int64(11*maxPods+255) * 1024 * 1024
+}
@@ -0,0 +1,67 @@
+package nodesource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAWSAllocatable(t *testing.T) {
+	tests := []struct {
+		name         string
+		instanceType string
+		kubelet      map[string]interface{}
+		cpu          string
+		memory       string
+		err          string
+	}{
+		// 6% of the first core and 1% of the second, 11Mi * 29 pods + 255Mi and 100Mi for eviction
+		{name: "m5.large", instanceType: "m5.large", cpu: "1930m", memory: "7698432Ki"},
+		// 0.5% of the next 2 cores, 11Mi * 58 pods + 255Mi and 100Mi for eviction
+		{name: "m5.xlarge", instanceType: "m5.xlarge", cpu: "3920m", memory: "15760384Ki"},
+		// 0.25% of the other 92 cores, 11Mi * 737 pods + 255Mi and 100Mi for eviction
+		{name: "m5.24xlarge", instanceType: "m5.24xlarge", cpu: "95690m", memory: "393988096Ki"},
+		{
+			name:         "overrides",
+			instanceType: "m5.large",
+			kubelet: map[string]interface{}{
+				"kubeReserved":   map[string]string{"cpu": "100m", "memory": "1Gi"},
+				"systemReserved": map[string]string{"cpu": "100m", "memory": "200Mi"},
+				"evictionHard":   map[string]string{"memory.available": "5%"},
+			},
+			cpu:    "1800m",
+			memory: "6715801Ki",
+		},
+		{
+			name:         "unsupported resource",
+			instanceType: "m5.large",
+			kubelet:      map[string]interface{}{"kubeReserved": map[string]string{"ephemeral-storage": "1Gi"}},
+			err:          "invalid kubelet: unsupported kube-reserved resource ephemeral-storage, expected cpu or memory",
+		},
+		{
+			name:         "invalid eviction threshold",
+			instanceType: "m5.large",
+			kubelet:      map[string]interface{}{"evictionHard": map[string]string{"memory.available": "150%"}},
+			err:          "invalid kubelet: invalid eviction threshold memory.available: 150%",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, err := getNodes(t, "aws", map[string]interface{}{
+				"region":        "us-east-1",
+				"instanceTypes": []string{test.instanceType},
+				"kubelet":       test.kubelet,
+			})
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			allocatable := nodes[0].GetNodeConfig("node-0").Status.Allocatable
+			assert.Equal(t, test.cpu, allocatable["cpu"])
+			assert.Equal(t, test.memory, allocatable["memory"])
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package nodesource
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	ec2instancesinfo "github.com/cristim/ec2-instances-info"
+	"github.com/pkg/errors"
+)
+
+// embeddedENIMaxPods is eni-max-pods.txt of amazon-eks-ami, as of commit cfab22a10647 (2024-02-29).
+//
+//go:embed eni-max-pods.txt
+var embeddedENIMaxPods string
+
+// Strategies of finding the max pods of instance types.
+const (
+	// MaxPodsENI uses eni-max-pods.txt, which is the max pods of the VPC CNI.
+	MaxPodsENI = "eni"
+	// MaxPodsPrefixDelegation is the max pods of the VPC CNI with prefix delegation.
+	MaxPodsPrefixDelegation = "prefix-delegation"
+	// MaxPodsFixed is the same max pods for all instance types, e.g for CNIs without ENI limits.
+	MaxPodsFixed = "fixed"
+	// MaxPodsFormula is the max pods of the VPC CNI, computed from the ENIs of instance types.
+	MaxPodsFormula = "formula"
+)
+
+// Max pods of prefix delegation of instance types with less than 30 vCPUs, and of the others.
+const (
+	prefixDelegationMaxPods      = 110
+	prefixDelegationLargeMaxPods = 250
+)
+
+// ipsPerPrefix is the number of IPs of every prefix that is delegated to an ENI.
+const ipsPerPrefix = 16
+
+// AWSMaxPods is how the max number of pods of every instance type is found.
+type AWSMaxPods struct {
+	// Strategy is eni (default), prefix-delegation, fixed or formula.
+	Strategy string `yaml:"strategy"`
+	// Value is the max pods of the fixed strategy, and overrides the max pods of prefix delegation
+	// (110, or 250 for instance types with at least 30 vCPUs).
+	Value int `yaml:"value"`
+	// ReservedENIs are ENIs that aren't used by pods in the formula and prefix-delegation
+	// strategies, e.g 1 with custom networking.
+	ReservedENIs int `yaml:"reservedENIs"`
+
+	// File is the path or URL of an eni-max-pods.txt file. By default, the copy in the binary is used.
+	File string `yaml:"file"`
+	// Formula computes the max pods of instance types that are missing from the file from their
+	// number of ENIs and IPs per ENI.
+	Formula bool `yaml:"formula"`
+}
+
+// needsENIs returns true if the number of ENIs of instance types is needed.
+func (m *AWSMaxPods) needsENIs() bool {
+	return m.Formula || m.Strategy == MaxPodsPrefixDelegation || m.Strategy == MaxPodsFormula
+}
+
+// maxPodsPerInstance returns the max pods of every instance type by the strategy.
+func (m *AWSMaxPods) maxPodsPerInstance(instances *ec2instancesinfo.InstanceData,
+	rawInstances []rawInstance) (map[string]int, error) {
+
+	if m.ReservedENIs < 0 {
+		return nil, errors.Errorf("reserved ENIs must be at least 0, got %d", m.ReservedENIs)
+	}
+	if m.ReservedENIs > 0 && m.Strategy != MaxPodsPrefixDelegation && m.Strategy != MaxPodsFormula {
+		return nil, errors.Errorf("reserved ENIs are only used by the formula and prefix-delegation strategies")
+	}
+
+	maxPodsPerInstance := map[string]int{}
+
+	switch m.Strategy {
+	case "", MaxPodsENI:
+		return m.eniMaxPodsPerInstance(rawInstances)
+
+	case MaxPodsFixed:
+		if m.Value <= 0 {
+			return nil, errors.Errorf("the fixed max pods strategy needs a positive value")
+		}
+
+		for _, instance := range *instances {
+			maxPodsPerInstance[instance.InstanceType] = m.Value
+		}
+
+	case MaxPodsFormula:
+		for _, instance := range rawInstances {
+			if instance.VPC.MaxENIs > m.ReservedENIs {
+				maxPodsPerInstance[instance.InstanceType] = eniMaxPods(instance.VPC.MaxENIs-m.ReservedENIs,
+					instance.VPC.IPsPerENI)
+			}
+		}
+
+	case MaxPodsPrefixDelegation:
+		vcpus := map[string]int{}
+		for _, instance := range *instances {
+			vcpus[instance.InstanceType] = instance.VCPU
+		}
+
+		for _, instance := range rawInstances {
+			if instance.VPC.MaxENIs <= m.ReservedENIs {
+				continue
+			}
+
+			maxPods := m.Value
+			if maxPods == 0 {
+				maxPods = prefixDelegationMaxPods
+				if vcpus[instance.InstanceType] >= 30 {
+					maxPods = prefixDelegationLargeMaxPods
+				}
+			}
+
+			enis := instance.VPC.MaxENIs - m.ReservedENIs
+			if limit := eniMaxPods(enis, (instance.VPC.IPsPerENI-1)*ipsPerPrefix+1); limit < maxPods {
+				maxPods = limit
+			}
+
+			maxPodsPerInstance[instance.InstanceType] = maxPods
+		}
+
+	default:
+		return nil, errors.Errorf("unknown max pods strategy %s, expected eni, prefix-delegation, fixed or formula",
+			m.Strategy)
+	}
+
+	return maxPodsPerInstance, nil
+}
+
+// eniMaxPodsPerInstance returns the max pods of every instance type in the file, and of every
+// other instance type in the instance data if Formula is set.
+func (m *AWSMaxPods) eniMaxPodsPerInstance(instances []rawInstance) (map[string]int, error) {
+	file, err := m.readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	maxPodsPerInstance, err := parseENIMaxPods(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Formula {
+		for _, instance := range instances {
+			if _, ok := maxPodsPerInstance[instance.InstanceType]; ok || instance.VPC.MaxENIs == 0 {
+				continue
+			}
+
+			maxPodsPerInstance[instance.InstanceType] = eniMaxPods(instance.VPC.MaxENIs, instance.VPC.IPsPerENI)
+		}
+	}
+
+	return maxPodsPerInstance, nil
+}
+
+// readFile returns the contents of the eni-max-pods.txt file.
+func (m *AWSMaxPods) readFile() (string, error) {
+	if m.File == "" {
+		return embeddedENIMaxPods, nil
+	}
+
+	var reader io.ReadCloser
+	if strings.HasPrefix(m.File, "http://") || strings.HasPrefix(m.File, "https://") {
+		response, err := http.Get(m.File)
+		if err != nil {
+			return "", errors.Wrap(err, "could not fetch max pods list")
+		}
+
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			return "", errors.Errorf("fetch max pods list did not return 200 (%d instead)", response.StatusCode)
+		}
+
+		reader = response.Body
+	} else {
+		file, err := os.Open(m.File)
+		if err != nil {
+			return "", errors.Wrap(err, "could not open max pods list")
+		}
+
+		reader = file
+	}
+	defer reader.Close()
+
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", errors.Wrap(err, "could not read max pods list")
+	}
+
+	return string(contents), nil
+}
+
+// parseENIMaxPods parses an eni-max-pods.txt file.
+func parseENIMaxPods(file string) (map[string]int, error) {
+	maxPodsPerInstance := make(map[string]int)
+
+	scanner := bufio.NewScanner(strings.NewReader(file))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Skip comments and empty lines
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		// Parse line
+		splitted := strings.Fields(line)
+		if len(splitted) != 2 {
+			return nil, errors.Errorf("could not parse eni-max-pods.txt file, bad line: %s", line)
+		}
+
+		instanceType := splitted[0]
+		maxPods, err := strconv.ParseInt(splitted[1], 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("could not parse eni-max-pods.txt file, bad line: %s", line)
+		}
+
+		maxPodsPerInstance[instanceType] = int(maxPods)
+	}
+
+	return maxPodsPerInstance, nil
+}
+
+// eniMaxPods returns the max pods of an instance type with the VPC CNI: every ENI has a primary
+// IP that isn't used by pods, and 2 host network pods (aws-node and kube-proxy) don't use IPs.
+func eniMaxPods(maxENIs int, ipsPerENI int) int {
+	return maxENIs*(ipsPerENI-1) + 2
+}
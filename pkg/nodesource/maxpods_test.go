@@ -0,0 +1,100 @@
+package nodesource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAWSMaxPods(t *testing.T) {
+	tests := []struct {
+		name         string
+		instanceType string
+		maxPods      map[string]interface{}
+		expected     int
+		err          string
+	}{
+		// 3 ENIs with 10 IPs each
+		{name: "eni", instanceType: "m5.large", expected: 29},
+		{name: "eni file", instanceType: "m5.large", maxPods: map[string]interface{}{"file": "testdata/eni-max-pods.txt"}, expected: 29},
+		{
+			name:         "eni missing instance type",
+			instanceType: "c5.large",
+			maxPods:      map[string]interface{}{"file": "testdata/eni-max-pods.txt"},
+			err:          "Could not find max pods for instance: c5.large",
+		},
+		{
+			name:         "eni missing instance type with formula",
+			instanceType: "c5.large",
+			maxPods:      map[string]interface{}{"file": "testdata/eni-max-pods.txt", "formula": true},
+			expected:     29,
+		},
+		{
+			name:         "eni invalid file",
+			instanceType: "m5.large",
+			maxPods:      map[string]interface{}{"file": "testdata/eni-max-pods-invalid.txt"},
+			err:          "could not get max pods per instance: could not parse eni-max-pods.txt file, bad line: m5.xlarge fifty-eight",
+		},
+		// 110 for instance types with less than 30 vCPUs
+		{name: "prefix delegation", instanceType: "m5.large", maxPods: map[string]interface{}{"strategy": "prefix-delegation"}, expected: 110},
+		// 250 for the others
+		{name: "prefix delegation large", instanceType: "m5.24xlarge", maxPods: map[string]interface{}{"strategy": "prefix-delegation"}, expected: 250},
+		// 2 ENIs with a single prefix of 16 IPs each
+		{name: "prefix delegation ENI limit", instanceType: "t3.nano", maxPods: map[string]interface{}{"strategy": "prefix-delegation"}, expected: 34},
+		{
+			name:         "prefix delegation reserved ENIs",
+			instanceType: "t3.nano",
+			maxPods:      map[string]interface{}{"strategy": "prefix-delegation", "reservedENIs": 1},
+			expected:     18,
+		},
+		{
+			name:         "prefix delegation value",
+			instanceType: "m5.24xlarge",
+			maxPods:      map[string]interface{}{"strategy": "prefix-delegation", "value": 737},
+			expected:     737,
+		},
+		{name: "fixed", instanceType: "m5.large", maxPods: map[string]interface{}{"strategy": "fixed", "value": 50}, expected: 50},
+		{
+			name:         "fixed without value",
+			instanceType: "m5.large",
+			maxPods:      map[string]interface{}{"strategy": "fixed"},
+			err:          "could not get max pods per instance: the fixed max pods strategy needs a positive value",
+		},
+		{name: "formula", instanceType: "m5.large", maxPods: map[string]interface{}{"strategy": "formula"}, expected: 29},
+		{
+			name:         "formula reserved ENIs",
+			instanceType: "m5.large",
+			maxPods:      map[string]interface{}{"strategy": "formula", "reservedENIs": 1},
+			expected:     20,
+		},
+		{
+			name:         "eni reserved ENIs",
+			instanceType: "m5.large",
+			maxPods:      map[string]interface{}{"reservedENIs": 1},
+			err:          "could not get max pods per instance: reserved ENIs are only used by the formula and prefix-delegation strategies",
+		},
+		{
+			name:         "unknown strategy",
+			instanceType: "m5.large",
+			maxPods:      map[string]interface{}{"strategy": "calico"},
+			err:          "could not get max pods per instance: unknown max pods strategy calico, expected eni, prefix-delegation, fixed or formula",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, err := getNodes(t, "aws", map[string]interface{}{
+				"region":        "us-east-1",
+				"instanceTypes": []string{test.instanceType},
+				"maxPods":       test.maxPods,
+			})
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, nodes[0].GetCapacity().MaxPods)
+		})
+	}
+}
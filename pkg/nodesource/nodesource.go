@@ -2,11 +2,54 @@ package nodesource
 
 import "github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
 
+// HoursPerMonth converts between hourly and monthly prices, with a month of 31 days, so monthly
+// prices such as EBS volumes are reported as they're listed.
+const HoursPerMonth = 24 * 31
+
 type Node interface {
+	// GetName returns the name of the node type, e.g an instance type.
+	GetName() string
+	// GetCapacity returns the resources of a node.
+	GetCapacity() Capacity
+	// GetPricing returns the prices of a node in different pricing models.
+	GetPricing() Pricing
 	GetHourlyPrice() float64
+	// GetGroupHourlyPrice returns the price per hour of a number of nodes, which isn't the price
+	// of a node times their number if some of them are reserved.
+	GetGroupHourlyPrice(nodeCount int) float64
+	// GetLabels returns the labels of the nodes, except their hostname and zone.
+	GetLabels() map[string]string
 	GetNodeConfig(nodeName string) *config.NodeConfig
 }
 
 type NodeSource interface {
 	GetNodes() ([]Node, error)
 }
+
+// BaselineNodeSource is a node source that can price the node types of an existing cluster,
+// which aren't searched unless they're returned by GetNodes as well.
+type BaselineNodeSource interface {
+	NodeSource
+	GetBaselineNodes(instanceTypes []string) ([]Node, error)
+}
+
+// Capacity is the resources of a node.
+type Capacity struct {
+	VCPU      int
+	MemoryGiB float32
+	GPU       int
+	MaxPods   int
+	// MaxNodes is the number of nodes of the type that are available, or 0 if it's unlimited.
+	MaxNodes int
+}
+
+// Pricing is the price per hour of a node in USD, in different pricing models.
+type Pricing struct {
+	OnDemand float64
+	// Spot is 0 if it's unknown.
+	Spot float64
+	// Storage is the price of the volumes of the node, which is paid in all pricing models.
+	Storage float64
+	// ReservedNodes is the number of nodes of a group that are priced by their reservation.
+	ReservedNodes int
+}
@@ -0,0 +1,82 @@
+package nodesource
+
+import "github.com/pkg/errors"
+
+// Pricing models of AWS nodes.
+const (
+	PricingOnDemand = "on-demand"
+	PricingSpot     = "spot"
+	PricingMixed    = "mixed"
+)
+
+// AWSPricing is how AWS nodes are paid for.
+type AWSPricing struct {
+	// Model is on-demand (default), spot or mixed.
+	Model string `yaml:"model"`
+	// SpotPercentage is the percentage of nodes that run on spot in the mixed model.
+	SpotPercentage float64 `yaml:"spotPercentage"`
+
+	// SpotPrices are the spot prices per hour of instance types, in USD. The instance data
+	// doesn't include spot prices, so they must be set for spot and mixed models, unless
+	// SpotDiscount is set.
+	SpotPrices map[string]float64 `yaml:"spotPrices"`
+	// SpotDiscount estimates the spot price of instance types without a spot price, as a
+	// percentage off the on-demand price (e.g 70).
+	SpotDiscount float64 `yaml:"spotDiscount"`
+
+	// Reserved nodes are priced by their reservation instead of the pricing model.
+	Reserved AWSReservation `yaml:"reserved"`
+}
+
+// spotPercentage returns the percentage of nodes that run on spot.
+func (p *AWSPricing) spotPercentage() (float64, error) {
+	percentage, err := p.modelSpotPercentage()
+	if err != nil {
+		return 0, err
+	}
+
+	if percentage > 0 && len(p.SpotPrices) == 0 && p.SpotDiscount == 0 {
+		return 0, errors.Errorf("spot prices aren't included in the instance data, please set spotPrices or spotDiscount "+
+			"for the %s pricing model", p.Model)
+	}
+
+	if p.SpotDiscount < 0 || p.SpotDiscount >= 100 {
+		return 0, errors.Errorf("spot discount must be at least 0 and less than 100, got %g", p.SpotDiscount)
+	}
+
+	return percentage, nil
+}
+
+// modelSpotPercentage returns the percentage of nodes that run on spot by the pricing model.
+func (p *AWSPricing) modelSpotPercentage() (float64, error) {
+	switch p.Model {
+	case "", PricingOnDemand:
+		return 0, nil
+
+	case PricingSpot:
+		return 100, nil
+
+	case PricingMixed:
+		if p.SpotPercentage < 0 || p.SpotPercentage > 100 {
+			return 0, errors.Errorf("spot percentage must be between 0 and 100, got %g", p.SpotPercentage)
+		}
+
+		return p.SpotPercentage, nil
+
+	default:
+		return 0, errors.Errorf("unknown pricing model %s, expected on-demand, spot or mixed", p.Model)
+	}
+}
+
+// spotPrice returns the spot price per hour of an instance type, or 0 if it's unknown.
+func (p *AWSPricing) spotPrice(instanceType string, onDemandPrice float64) float64 {
+	if price, ok := p.SpotPrices[instanceType]; ok {
+		return price
+	}
+
+	if p.SpotDiscount == 0 {
+		return 0
+	}
+
+	return onDemandPrice * (1 - p.SpotDiscount/100)
+}
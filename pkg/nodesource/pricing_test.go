@@ -0,0 +1,90 @@
+package nodesource_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAWSSpotPrices(t *testing.T) {
+	tests := []struct {
+		name     string
+		pricing  map[string]interface{}
+		expected float64
+		err      string
+	}{
+		{
+			name:     "spot prices",
+			pricing:  map[string]interface{}{"model": "spot", "spotPrices": map[string]float64{"m5.large": 0.04}},
+			expected: 0.04,
+		},
+		{
+			name:     "spot discount",
+			pricing:  map[string]interface{}{"model": "spot", "spotDiscount": 50},
+			expected: 0.048,
+		},
+		{
+			name:    "no spot prices",
+			pricing: map[string]interface{}{"model": "mixed", "spotPercentage": 50},
+			err:     "spot prices aren't included in the instance data, please set spotPrices or spotDiscount for the mixed pricing model",
+		},
+		{
+			name:    "missing spot price",
+			pricing: map[string]interface{}{"model": "spot", "spotPrices": map[string]float64{"c5.large": 0.03}},
+			err:     "unknown spot price of m5.large, which isn't included in the instance data, please set it in spotPrices or set spotDiscount",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodes, err := getNodes(t, "aws", map[string]interface{}{
+				"region":        "us-east-1",
+				"instanceTypes": []string{"m5.large"},
+				"pricing":       test.pricing,
+			})
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.InDelta(t, test.expected, nodes[0].GetPricing().Spot, 1e-9)
+		})
+	}
+}
+
+func TestAWSReservedNodeGroups(t *testing.T) {
+	reserved := map[string]interface{}{
+		"reserved": map[string]interface{}{"nodes": map[string]int{"m5.large": 2}, "term": "1y"},
+	}
+
+	// A reserved instance type in both instanceTypes and a node group
+	_, err := getNodes(t, "aws", map[string]interface{}{
+		"region":        "us-east-1",
+		"instanceTypes": []string{"m5.large"},
+		"nodeGroups":    []map[string]interface{}{{"instanceTypes": []string{"m5.large"}, "labels": map[string]string{"team": "ml"}}},
+		"pricing":       reserved,
+	})
+	assert.EqualError(t, err, "reserved instance type m5.large can't be in more than one node group, its nodes are reserved once per cluster")
+
+	// A reserved instance type in two node groups
+	_, err = getNodes(t, "aws", map[string]interface{}{
+		"region": "us-east-1",
+		"nodeGroups": []map[string]interface{}{
+			{"instanceTypes": []string{"m5.large"}, "labels": map[string]string{"team": "ml"}},
+			{"instanceTypes": []string{"m5.*large"}, "labels": map[string]string{"team": "web"}},
+		},
+		"pricing": reserved,
+	})
+	assert.EqualError(t, err, "reserved instance type m5.large can't be in more than one node group, its nodes are reserved once per cluster")
+
+	// Instance types that aren't reserved may be in several node groups
+	nodes, err := getNodes(t, "aws", map[string]interface{}{
+		"region":        "us-east-1",
+		"instanceTypes": []string{"m5.large", "c5.large"},
+		"nodeGroups":    []map[string]interface{}{{"instanceTypes": []string{"c5.large"}, "labels": map[string]string{"team": "ml"}}},
+		"pricing":       reserved,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"m5.large", "c5.large", "c5.large"}, nodeNames(nodes))
+}
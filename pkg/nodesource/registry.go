@@ -0,0 +1,75 @@
+package nodesource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Factory creates a node source from its section of the nodes config. unmarshal decodes the
+// section into a value, like in yaml.Unmarshaler.
+type Factory func(unmarshal func(interface{}) error, options Options) (NodeSource, error)
+
+// Options are the settings of node sources that don't come from their section.
+type Options struct {
+	// Path returns the path of a file that's relative to the config file.
+	Path func(path string) string
+	// Region and InstanceTypes are the defaults of node sources without a region and instance
+	// types, e.g the ones of an existing cluster.
+	Region        string
+	InstanceTypes []string
+}
+
+var factories = map[string]Factory{}
+
+// Register registers the factory of a node source by the name of its section, e.g aws for
+// nodes.aws. It panics if the name is already registered.
+func Register(name string, factory Factory) {
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("node source %s is already registered", name))
+	}
+
+	factories[name] = factory
+}
+
+// Names returns the names of the registered node sources, sorted.
+func Names() []string {
+	names := []string{}
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// New creates a node source from its section of the nodes config.
+func New(name string, section interface{}, options Options) (NodeSource, error) {
+	factory, ok := factories[name]
+	if !ok {
+		return nil, errors.Errorf("unknown node source %s, expected one of %s", name, strings.Join(Names(), ", "))
+	}
+
+	data, err := yaml.Marshal(section)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid nodes.%s", name)
+	}
+
+	unmarshal := func(out interface{}) error {
+		return yaml.Unmarshal(data, out)
+	}
+
+	if options.Path == nil {
+		options.Path = func(path string) string { return path }
+	}
+
+	source, err := factory(unmarshal, options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid nodes.%s", name)
+	}
+
+	return source, nil
+}
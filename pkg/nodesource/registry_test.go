@@ -0,0 +1,48 @@
+package nodesource_test
+
+import (
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/stretchr/testify/assert"
+)
+
+// newNodeSource creates a node source from a config section, like the sections of nodes.
+func newNodeSource(t *testing.T, name string, section map[string]interface{}) nodesource.NodeSource {
+	source, err := nodesource.New(name, section, nodesource.Options{})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	return source
+}
+
+// getNodes returns the node types of a node source that is created from a config section.
+func getNodes(t *testing.T, name string, section map[string]interface{}) ([]nodesource.Node, error) {
+	return newNodeSource(t, name, section).GetNodes()
+}
+
+// nodeNames returns the names of node types.
+func nodeNames(nodes []nodesource.Node) []string {
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.GetName())
+	}
+
+	return names
+}
+
+func TestNew(t *testing.T) {
+	nodes, err := getNodes(t, "static", map[string]interface{}{
+		"shapes": []map[string]interface{}{{"name": "r650", "cpu": 64, "memory": "512Gi", "hourlyCost": 1.1}},
+	})
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "r650", nodes[0].GetName())
+	assert.Equal(t, "r650", nodes[0].GetLabels()["node.kubernetes.io/instance-type"])
+}
+
+func TestNewUnknown(t *testing.T) {
+	_, err := nodesource.New("oracle", nil, nodesource.Options{})
+	assert.EqualError(t, err, "unknown node source oracle, expected one of aws, azure, exec, gcp, static")
+}
@@ -0,0 +1,94 @@
+package nodesource
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// AWSReservation is a reservation of the first nodes of some instance types, with reserved
+// instances or the equivalent Savings Plan.
+type AWSReservation struct {
+	// Nodes is the number of reserved nodes of every reserved instance type. The nodes of an
+	// instance type are reserved once per cluster, so a reserved instance type must not be in
+	// more than one node group.
+	Nodes map[string]int `yaml:"nodes"`
+	// Term is 1y or 3y.
+	Term string `yaml:"term"`
+	// Offering is standard (default) or convertible.
+	Offering string `yaml:"offering"`
+	// Payment is no-upfront (default), partial-upfront or all-upfront.
+	Payment string `yaml:"payment"`
+}
+
+// validate returns an error if a reserved node count is negative.
+func (r *AWSReservation) validate() error {
+	instanceTypes := []string{}
+	for instanceType := range r.Nodes {
+		instanceTypes = append(instanceTypes, instanceType)
+	}
+	sort.Strings(instanceTypes)
+
+	for _, instanceType := range instanceTypes {
+		if r.Nodes[instanceType] < 0 {
+			return errors.Errorf("reserved nodes of %s must be at least 0, got %d", instanceType, r.Nodes[instanceType])
+		}
+	}
+
+	return nil
+}
+
+// pricingKey returns the key of the reserved price in the instance data, e.g yrTerm1Standard.allUpfront.
+func (r *AWSReservation) pricingKey() (string, error) {
+	terms := map[string]string{"1y": "yrTerm1", "3y": "yrTerm3"}
+	offerings := map[string]string{"": "Standard", "standard": "Standard", "convertible": "Convertible"}
+	payments := map[string]string{
+		"":                "noUpfront",
+		"no-upfront":      "noUpfront",
+		"partial-upfront": "partialUpfront",
+		"all-upfront":     "allUpfront",
+	}
+
+	term, ok := terms[r.Term]
+	if !ok {
+		return "", errors.Errorf("unknown reservation term %s, expected 1y or 3y", r.Term)
+	}
+
+	offering, ok := offerings[r.Offering]
+	if !ok {
+		return "", errors.Errorf("unknown reservation offering %s, expected standard or convertible", r.Offering)
+	}
+
+	payment, ok := payments[r.Payment]
+	if !ok {
+		return "", errors.Errorf("unknown reservation payment %s, expected no-upfront, partial-upfront or all-upfront",
+			r.Payment)
+	}
+
+	return term + offering + "." + payment, nil
+}
+
+// reservedPrices returns the reserved price per hour of every instance type in a region. It's the
+// effective hourly rate of the instance data, which already includes the upfront payment.
+func (r *AWSReservation) reservedPrices(region string, instances []rawInstance) (map[string]float64, error) {
+	key, err := r.pricingKey()
+	if err != nil {
+		return nil, err
+	}
+
+	prices := map[string]float64{}
+	for _, instance := range instances {
+		price, ok := instance.Pricing[region].Linux.Reserved[key]
+		if !ok {
+			continue
+		}
+
+		prices[instance.InstanceType], err = strconv.ParseFloat(price, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse reserved price of %s", instance.InstanceType)
+		}
+	}
+
+	return prices, nil
+}
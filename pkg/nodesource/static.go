@@ -0,0 +1,407 @@
+package nodesource
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pfnet-research/k8s-cluster-simulator/pkg/config"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// staticDefaultMaxPods is the max pods of static nodes without max pods.
+const staticDefaultMaxPods = 110
+
+// staticDefaultRegion is the region of static nodes without a region, so that their nodes are
+// spread across zones like cloud nodes.
+const staticDefaultRegion = "on-prem"
+
+// StaticNodeShape is a node type of an on-prem or otherwise fixed catalog, e.g a hardware SKU.
+type StaticNodeShape struct {
+	Name string `json:"name" yaml:"name"`
+	// CPU and Memory are the allocatable resources of a node, e.g 32 and 128Gi.
+	CPU     string `json:"cpu" yaml:"cpu"`
+	Memory  string `json:"memory" yaml:"memory"`
+	GPU     int    `json:"gpu" yaml:"gpu"`
+	MaxPods int    `json:"maxPods" yaml:"maxPods"`
+	// HourlyCost is the price per hour of a node, e.g an internal chargeback rate.
+	HourlyCost float64           `json:"hourlyCost" yaml:"hourlyCost"`
+	Labels     map[string]string `json:"labels" yaml:"labels"`
+	Taints     []string          `json:"taints" yaml:"taints"`
+	// MaxCount is the number of nodes that are available, or 0 if it's unlimited.
+	MaxCount int `json:"maxCount" yaml:"maxCount"`
+	// Region is on-prem by default. Nodes are spread across the zones of their region, unless
+	// Zone puts all of them in one zone.
+	Region string `json:"region" yaml:"region"`
+	Zone   string `json:"zone" yaml:"zone"`
+}
+
+// StaticNode is a node type of a static catalog.
+type StaticNode struct {
+	Name       string            `json:"name"`
+	CPU        resource.Quantity `json:"cpu"`
+	Memory     resource.Quantity `json:"memory"`
+	GPU        int               `json:"gpu"`
+	MaxPods    int               `json:"maxPods"`
+	HourlyCost float64           `json:"hourlyCost"`
+	MaxCount   int               `json:"maxCount"`
+	Region     string            `json:"region"`
+	Zone       string            `json:"zone,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Taints     []v1.Taint        `json:"taints,omitempty"`
+}
+
+type StaticNodeSource struct {
+	Shapes []StaticNodeShape `yaml:"shapes"`
+	// File is a CSV or YAML file of more shapes, which are returned after Shapes.
+	File string `yaml:"file"`
+}
+
+func init() {
+	Register("static", newStaticNodeSource)
+}
+
+// newStaticNodeSource creates a static node source from the nodes.static section.
+func newStaticNodeSource(unmarshal func(interface{}) error, options Options) (NodeSource, error) {
+	s := &StaticNodeSource{}
+	if err := unmarshal(s); err != nil {
+		return nil, err
+	}
+
+	if s.File != "" {
+		s.File = options.Path(s.File)
+	}
+
+	return s, nil
+}
+
+func (s *StaticNodeSource) GetNodes() ([]Node, error) {
+	shapes := append([]StaticNodeShape{}, s.Shapes...)
+
+	if s.File != "" {
+		fileShapes, err := readStaticNodeShapes(s.File)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read node shapes from %s", s.File)
+		}
+
+		shapes = append(shapes, fileShapes...)
+	}
+
+	if len(shapes) == 0 {
+		return nil, errors.New("no static node shapes")
+	}
+
+	return shapeNodes(shapes)
+}
+
+// GetBaselineNodes returns the node types of shapes by their names.
+func (s *StaticNodeSource) GetBaselineNodes(names []string) ([]Node, error) {
+	nodes, err := s.GetNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	return baselineNodes(nodes, names)
+}
+
+// shapeNodes returns the node types of shapes, whose names must be unique.
+func shapeNodes(shapes []StaticNodeShape) ([]Node, error) {
+	names := map[string]bool{}
+	nodes := []Node{}
+	for _, shape := range shapes {
+		if names[shape.Name] {
+			return nil, errors.Errorf("duplicate node shape %s", shape.Name)
+		}
+		names[shape.Name] = true
+
+		node, err := shape.node()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid node shape %s", shape.Name)
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// baselineNodes returns node types by their names.
+func baselineNodes(nodes []Node, names []string) ([]Node, error) {
+	nodesByName := map[string]Node{}
+	for _, node := range nodes {
+		nodesByName[node.GetName()] = node
+	}
+
+	baseline := []Node{}
+	for _, name := range names {
+		node, ok := nodesByName[name]
+		if !ok {
+			return nil, errors.Errorf("unknown node shape %s", name)
+		}
+
+		baseline = append(baseline, node)
+	}
+
+	return baseline, nil
+}
+
+// node validates the shape and returns its node type.
+func (s *StaticNodeShape) node() (*StaticNode, error) {
+	if s.Name == "" {
+		return nil, errors.New("name is empty")
+	}
+
+	cpu, err := resource.ParseQuantity(s.CPU)
+	if err != nil || cpu.Sign() <= 0 {
+		return nil, errors.Errorf("invalid cpu %q", s.CPU)
+	}
+
+	memory, err := resource.ParseQuantity(s.Memory)
+	if err != nil || memory.Sign() <= 0 {
+		return nil, errors.Errorf("invalid memory %q", s.Memory)
+	}
+
+	if s.GPU < 0 || s.MaxPods < 0 || s.MaxCount < 0 || s.HourlyCost < 0 {
+		return nil, errors.New("gpu, maxPods, maxCount and hourlyCost can't be negative")
+	}
+
+	maxPods := s.MaxPods
+	if maxPods == 0 {
+		maxPods = staticDefaultMaxPods
+	}
+
+	region := s.Region
+	if region == "" {
+		region = staticDefaultRegion
+	}
+
+	taints := []v1.Taint{}
+	for _, taintString := range s.Taints {
+		taint, err := ParseTaint(taintString)
+		if err != nil {
+			return nil, err
+		}
+
+		taints = append(taints, taint)
+	}
+
+	return &StaticNode{
+		Name:       s.Name,
+		CPU:        cpu,
+		Memory:     memory,
+		GPU:        s.GPU,
+		MaxPods:    maxPods,
+		HourlyCost: s.HourlyCost,
+		MaxCount:   s.MaxCount,
+		Region:     region,
+		Zone:       s.Zone,
+		Labels:     s.Labels,
+		Taints:     taints,
+	}, nil
+}
+
+// readStaticNodeShapes reads node shapes from a YAML file with a list of shapes, or from a CSV
+// file with a header.
+func readStaticNodeShapes(path string) ([]StaticNodeShape, error) {
+	if strings.ToLower(filepath.Ext(path)) == ".csv" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		return ParseStaticNodeShapesCSV(file)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	shapes := []StaticNodeShape{}
+	if err := yaml.UnmarshalStrict(data, &shapes); err != nil {
+		return nil, err
+	}
+
+	return shapes, nil
+}
+
+// ParseStaticNodeShapesCSV parses node shapes from CSV with a header of the columns name, cpu,
+// memory, gpu, max_pods, hourly_cost, max_count, region, zone, labels and taints in any order. Only name,
+// cpu, memory and hourly_cost are required. Labels are separated by semicolons, e.g
+// "rack=a;disk=ssd", and so are taints.
+func ParseStaticNodeShapesCSV(r io.Reader) ([]StaticNodeShape, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("missing CSV header")
+	}
+
+	columns := map[string]int{}
+	for i, column := range records[0] {
+		switch column {
+		case "name", "cpu", "memory", "gpu", "max_pods", "hourly_cost", "max_count", "region", "zone", "labels", "taints":
+			columns[column] = i
+		default:
+			return nil, errors.Errorf("unknown column %s", column)
+		}
+	}
+
+	for _, column := range []string{"name", "cpu", "memory", "hourly_cost"} {
+		if _, ok := columns[column]; !ok {
+			return nil, errors.Errorf("missing column %s", column)
+		}
+	}
+
+	shapes := []StaticNodeShape{}
+	for i, record := range records[1:] {
+		line := i + 2
+		value := func(column string) string {
+			if index, ok := columns[column]; ok {
+				return strings.TrimSpace(record[index])
+			}
+
+			return ""
+		}
+
+		shape := StaticNodeShape{
+			Name:   value("name"),
+			CPU:    value("cpu"),
+			Memory: value("memory"),
+			Region: value("region"),
+			Zone:   value("zone"),
+		}
+
+		intColumns := []struct {
+			name  string
+			field *int
+		}{
+			{"gpu", &shape.GPU},
+			{"max_pods", &shape.MaxPods},
+			{"max_count", &shape.MaxCount},
+		}
+		for _, column := range intColumns {
+			if value(column.name) == "" {
+				continue
+			}
+
+			if *column.field, err = strconv.Atoi(value(column.name)); err != nil {
+				return nil, errors.Errorf("line %d: invalid %s %q", line, column.name, value(column.name))
+			}
+		}
+
+		if shape.HourlyCost, err = strconv.ParseFloat(value("hourly_cost"), 64); err != nil {
+			return nil, errors.Errorf("line %d: invalid hourly_cost %q", line, value("hourly_cost"))
+		}
+
+		if labels := value("labels"); labels != "" {
+			shape.Labels = map[string]string{}
+			for _, label := range strings.Split(labels, ";") {
+				keyValue := strings.SplitN(label, "=", 2)
+				if len(keyValue) != 2 || keyValue[0] == "" {
+					return nil, errors.Errorf("line %d: invalid label %q, expected key=value", line, label)
+				}
+
+				shape.Labels[keyValue[0]] = keyValue[1]
+			}
+		}
+
+		if taints := value("taints"); taints != "" {
+			shape.Taints = strings.Split(taints, ";")
+		}
+
+		shapes = append(shapes, shape)
+	}
+
+	return shapes, nil
+}
+
+func (n *StaticNode) GetName() string {
+	return n.Name
+}
+
+func (n *StaticNode) GetCapacity() Capacity {
+	return Capacity{
+		VCPU:      int(math.Ceil(float64(n.CPU.MilliValue()) / 1000)),
+		MemoryGiB: float32(float64(n.Memory.Value()) / (1024 * 1024 * 1024)),
+		GPU:       n.GPU,
+		MaxPods:   n.MaxPods,
+		MaxNodes:  n.MaxCount,
+	}
+}
+
+func (n *StaticNode) GetPricing() Pricing {
+	return Pricing{OnDemand: n.HourlyCost}
+}
+
+func (n *StaticNode) GetHourlyPrice() float64 {
+	return n.HourlyCost
+}
+
+func (n *StaticNode) GetGroupHourlyPrice(nodeCount int) float64 {
+	return float64(nodeCount) * n.HourlyCost
+}
+
+// GetNodeConfig returns a node whose allocatable resources are the resources of the shape.
+func (n *StaticNode) GetNodeConfig(nodeName string) *config.NodeConfig {
+	labels := n.GetLabels()
+	labels["kubernetes.io/hostname"] = nodeName
+	if n.Zone != "" {
+		labels["topology.kubernetes.io/zone"] = n.Zone
+		labels["failure-domain.beta.kubernetes.io/zone"] = n.Zone
+	}
+
+	return &config.NodeConfig{
+		Metadata: metav1.ObjectMeta{
+			Name:   nodeName,
+			Labels: labels,
+		},
+		Spec: v1.NodeSpec{
+			Unschedulable: false,
+			Taints:        n.Taints,
+		},
+		Status: config.NodeStatus{
+			Allocatable: map[v1.ResourceName]string{
+				"cpu":            n.CPU.String(),
+				"memory":         n.Memory.String(),
+				"nvidia.com/gpu": fmt.Sprintf("%d", n.GPU),
+				"pods":           fmt.Sprintf("%d", n.MaxPods),
+			},
+		},
+	}
+}
+
+// GetLabels returns the well-known labels of the node, followed by the labels of its shape.
+func (n *StaticNode) GetLabels() map[string]string {
+	labels := map[string]string{
+		"kubernetes.io/os":                         "linux",
+		"beta.kubernetes.io/os":                    "linux",
+		"kubernetes.io/arch":                       "amd64",
+		"beta.kubernetes.io/arch":                  "amd64",
+		"node.kubernetes.io/instance-type":         n.Name,
+		"beta.kubernetes.io/instance-type":         n.Name,
+		"topology.kubernetes.io/region":            n.Region,
+		"failure-domain.beta.kubernetes.io/region": n.Region,
+	}
+
+	for key, value := range n.Labels {
+		labels[key] = value
+	}
+
+	return labels
+}
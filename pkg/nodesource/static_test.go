@@ -0,0 +1,91 @@
+package nodesource_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStaticNodeShapesCSV(t *testing.T) {
+	shapes, err := nodesource.ParseStaticNodeShapesCSV(strings.NewReader(`name,cpu,memory,hourly_cost,gpu,max_count,labels,taints
+r650,64,512Gi,1.1,0,2,rack=a;disk=nvme,
+dgx,128,1Ti,20,8,,,nvidia.com/gpu:NoSchedule;team=ml:NoExecute
+`))
+	assert.Nil(t, err)
+
+	assert.Equal(t, []nodesource.StaticNodeShape{
+		{
+			Name:       "r650",
+			CPU:        "64",
+			Memory:     "512Gi",
+			HourlyCost: 1.1,
+			MaxCount:   2,
+			Labels:     map[string]string{"rack": "a", "disk": "nvme"},
+		},
+		{
+			Name:       "dgx",
+			CPU:        "128",
+			Memory:     "1Ti",
+			GPU:        8,
+			HourlyCost: 20,
+			Taints:     []string{"nvidia.com/gpu:NoSchedule", "team=ml:NoExecute"},
+		},
+	}, shapes)
+}
+
+func TestParseStaticNodeShapesCSVErrors(t *testing.T) {
+	_, err := nodesource.ParseStaticNodeShapesCSV(strings.NewReader("name,cpu,memory\n"))
+	assert.EqualError(t, err, "missing column hourly_cost")
+
+	_, err = nodesource.ParseStaticNodeShapesCSV(strings.NewReader("name,cpu,memory,hourly_cost,disks\n"))
+	assert.EqualError(t, err, "unknown column disks")
+
+	_, err = nodesource.ParseStaticNodeShapesCSV(strings.NewReader("name,cpu,memory,hourly_cost,gpu\nr650,64,512Gi,1.1,two\n"))
+	assert.EqualError(t, err, `line 2: invalid gpu "two"`)
+
+	_, err = nodesource.ParseStaticNodeShapesCSV(strings.NewReader("name,cpu,memory,hourly_cost,labels\nr650,64,512Gi,1.1,rack\n"))
+	assert.EqualError(t, err, `line 2: invalid label "rack", expected key=value`)
+}
+
+func TestStaticNodeSource(t *testing.T) {
+	source := &nodesource.StaticNodeSource{Shapes: []nodesource.StaticNodeShape{
+		{Name: "r650", CPU: "7500m", Memory: "64Gi", HourlyCost: 1.1, MaxCount: 2},
+	}}
+
+	nodes, err := source.GetNodes()
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 1)
+
+	assert.Equal(t, nodesource.Capacity{VCPU: 8, MemoryGiB: 64, MaxPods: 110, MaxNodes: 2}, nodes[0].GetCapacity())
+	assert.Equal(t, 2.2, nodes[0].GetGroupHourlyPrice(2))
+
+	node := nodes[0].GetNodeConfig("node-0")
+	assert.Equal(t, "7500m", node.Status.Allocatable["cpu"])
+	assert.Equal(t, "64Gi", node.Status.Allocatable["memory"])
+	assert.Equal(t, "r650", node.Metadata.Labels["node.kubernetes.io/instance-type"])
+
+	source.Shapes = append(source.Shapes, nodesource.StaticNodeShape{Name: "r650", CPU: "1", Memory: "1Gi"})
+	_, err = source.GetNodes()
+	assert.EqualError(t, err, "duplicate node shape r650")
+}
+
+func TestStaticNodeRegionAndZone(t *testing.T) {
+	source := &nodesource.StaticNodeSource{Shapes: []nodesource.StaticNodeShape{
+		{Name: "r650", CPU: "64", Memory: "512Gi", HourlyCost: 1.1},
+		{Name: "r640", CPU: "32", Memory: "192Gi", HourlyCost: 0.4, Region: "dc1", Zone: "dc1-hall2"},
+	}}
+
+	nodes, err := source.GetNodes()
+	assert.Nil(t, err)
+
+	// Nodes without a zone are spread across the zones of their region by the simulator
+	labels := nodes[0].GetNodeConfig("node-0").Metadata.Labels
+	assert.Equal(t, "on-prem", labels["topology.kubernetes.io/region"])
+	assert.Empty(t, labels["topology.kubernetes.io/zone"])
+
+	labels = nodes[1].GetNodeConfig("node-1").Metadata.Labels
+	assert.Equal(t, "dc1", labels["topology.kubernetes.io/region"])
+	assert.Equal(t, "dc1-hall2", labels["topology.kubernetes.io/zone"])
+}
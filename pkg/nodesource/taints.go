@@ -0,0 +1,39 @@
+package nodesource
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ParseTaint parses a taint in the format of kubectl taint, e.g nvidia.com/gpu=true:NoSchedule.
+// The value is optional.
+func ParseTaint(s string) (v1.Taint, error) {
+	taint := v1.Taint{}
+
+	separator := strings.LastIndex(s, ":")
+	if separator == -1 {
+		return taint, errors.Errorf("invalid taint %s, expected key[=value]:effect", s)
+	}
+
+	taint.Effect = v1.TaintEffect(s[separator+1:])
+	switch taint.Effect {
+	case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+	default:
+		return taint, errors.Errorf("invalid taint effect %s, expected NoSchedule, PreferNoSchedule or NoExecute",
+			taint.Effect)
+	}
+
+	keyValue := strings.SplitN(s[:separator], "=", 2)
+	taint.Key = keyValue[0]
+	if len(keyValue) == 2 {
+		taint.Value = keyValue[1]
+	}
+
+	if taint.Key == "" {
+		return taint, errors.Errorf("invalid taint %s, key is empty", s)
+	}
+
+	return taint, nil
+}
@@ -0,0 +1,32 @@
+#!/bin/sh
+# A node source plugin for tests. The first argument selects its behavior.
+request=$(cat)
+
+case "$1" in
+ok)
+  region=$(echo "$request" | sed -n 's/.*"region":"\([^"]*\)".*/\1/p')
+  family=$(echo "$request" | sed -n 's/.*"family":"\([^"]*\)".*/\1/p')
+  cat <<JSON
+{
+  "apiVersion": "kubesurvival/v1",
+  "nodes": [
+    {"name": "$family-large", "cpu": "2", "memory": "8Gi", "hourlyCost": 0.08, "labels": {"region": "$region"}},
+    {"name": "$family-xlarge", "cpu": "4", "memory": "16Gi", "hourlyCost": 0.16, "maxCount": 3}
+  ]
+}
+JSON
+  ;;
+error)
+  echo '{"apiVersion": "kubesurvival/v1", "error": "no prices for region"}'
+  ;;
+version)
+  echo '{"apiVersion": "kubesurvival/v2", "nodes": []}'
+  ;;
+fail)
+  echo "could not connect to the pricing system" >&2
+  exit 1
+  ;;
+sleep)
+  sleep 5
+  ;;
+esac
@@ -0,0 +1,221 @@
+package optimizer
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+)
+
+const (
+	defaultMaxNodeGroups    = 2
+	defaultMaxNodesPerGroup = 30
+)
+
+// FindCheapestMix returns the cheapest cluster made of up to MaxNodeGroups node groups, each
+// of a different node type. Single node type clusters are considered as well, so the result
+// is never more expensive than the one of FindCheapest.
+func (o *Optimizer) FindCheapestMix() (*Result, error) {
+	maxNodeGroups := o.MaxNodeGroups
+	if maxNodeGroups == 0 {
+		maxNodeGroups = defaultMaxNodeGroups
+	}
+
+	// The cheapest single node type clusters are a good upper bound for the search
+	_, err := o.FindCheapest()
+	if err != nil && err != ErrNoNodeTypes {
+		return nil, err
+	}
+
+	// Find which node types can run each pod
+	nodeTypes := []nodesource.Node{}
+	podFits := make([][]bool, len(o.Pods))
+	for _, nodeType := range o.NodeTypes {
+		fits := make([]bool, len(o.Pods))
+		fitsAnyPod := false
+		for i, pod := range o.Pods {
+			fits[i] = podFitsNodeType(pod, nodeType) == ""
+			fitsAnyPod = fitsAnyPod || fits[i]
+		}
+
+		if !fitsAnyPod {
+			continue
+		}
+
+		for i := range o.Pods {
+			podFits[i] = append(podFits[i], fits[i])
+		}
+		nodeTypes = append(nodeTypes, nodeType)
+	}
+
+	for size := 2; size <= maxNodeGroups && size <= len(nodeTypes); size++ {
+		for _, combination := range combinations(len(nodeTypes), size) {
+			// Every pod must fit in at least one node type of the mix
+			if !coversAllPods(podFits, combination) {
+				continue
+			}
+
+			mixNodeTypes := []nodesource.Node{}
+			for _, i := range combination {
+				mixNodeTypes = append(mixNodeTypes, nodeTypes[i])
+			}
+
+			mixResult, err := o.findCheapestNodeCounts(mixNodeTypes)
+			if err != nil {
+				return nil, err
+			}
+
+			if mixResult != nil {
+				o.addResult(mixResult)
+			}
+		}
+	}
+
+	return o.cheapest(), nil
+}
+
+// findCheapestNodeCounts finds the cheapest node count for each of the given node types, or
+// returns nil if there's no such cluster that is cheaper than the ranked results.
+//
+// Node count assignments are simulated in order of increasing price (uniform-cost search),
+// so the first successful simulation is the cheapest one. This assumes that adding nodes
+// to a cluster never causes pods to become pending.
+func (o *Optimizer) findCheapestNodeCounts(nodeTypes []nodesource.Node) (*Result, error) {
+	maxNodesPerGroup := o.MaxNodesPerGroup
+	if maxNodesPerGroup == 0 {
+		maxNodesPerGroup = defaultMaxNodesPerGroup
+	}
+
+	// Every node group has at least one node
+	initialCounts := make([]int, len(nodeTypes))
+	for i := range initialCounts {
+		initialCounts[i] = 1
+	}
+
+	queue := &candidateQueue{}
+	visited := map[string]bool{}
+	heap.Push(queue, newCandidate(nodeTypes, initialCounts))
+
+	for queue.Len() > 0 {
+		candidate := heap.Pop(queue).(*candidate)
+
+		// Do we even need to simulate?
+		if maxPrice, ok := o.maxPrice(); ok && candidate.totalPricePerMonth >= maxPrice {
+			return nil, nil
+		}
+
+		simulationResult, err := o.simulate(candidate.groups...)
+		if err != nil {
+			return nil, err
+		}
+
+		if simulationResult.Successful {
+			return &Result{
+				NodeGroups:         candidate.groups,
+				TotalPricePerMonth: candidate.totalPricePerMonth,
+				Nodes:              simulationResult.Nodes,
+				StrandedCapacity:   simulationResult.StrandedCapacity,
+			}, nil
+		}
+
+		// Try adding a node to each of the node groups
+		for i := range nodeTypes {
+			counts := candidate.counts()
+			counts[i]++
+
+			key := fmt.Sprint(counts)
+			if counts[i] > maxNodesPerGroup || visited[key] {
+				continue
+			}
+			if maxNodes := nodeTypes[i].GetCapacity().MaxNodes; maxNodes > 0 && counts[i] > maxNodes {
+				continue
+			}
+
+			visited[key] = true
+			heap.Push(queue, newCandidate(nodeTypes, counts))
+		}
+	}
+
+	return nil, nil
+}
+
+// coversAllPods checks whether every pod fits in at least one of the node types.
+func coversAllPods(podFits [][]bool, nodeTypeIndices []int) bool {
+	for _, fits := range podFits {
+		covered := false
+		for _, i := range nodeTypeIndices {
+			if fits[i] {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			return false
+		}
+	}
+
+	return true
+}
+
+// combinations returns all subsets of size k of the numbers 0..n-1.
+func combinations(n, k int) [][]int {
+	result := [][]int{}
+
+	var generate func(start int, current []int)
+	generate = func(start int, current []int) {
+		if len(current) == k {
+			result = append(result, append([]int{}, current...))
+			return
+		}
+
+		for i := start; i < n; i++ {
+			generate(i+1, append(current, i))
+		}
+	}
+
+	generate(0, []int{})
+	return result
+}
+
+// candidate is a cluster of node groups waiting to be simulated.
+type candidate struct {
+	groups             []NodeGroup
+	totalPricePerMonth float64
+}
+
+func newCandidate(nodeTypes []nodesource.Node, counts []int) *candidate {
+	groups := []NodeGroup{}
+	for i, nodeType := range nodeTypes {
+		groups = append(groups, NodeGroup{NodeType: nodeType, NodeCount: counts[i]})
+	}
+
+	return &candidate{
+		groups:             groups,
+		totalPricePerMonth: PricePerMonth(groups...),
+	}
+}
+
+func (c *candidate) counts() []int {
+	counts := []int{}
+	for _, group := range c.groups {
+		counts = append(counts, group.NodeCount)
+	}
+
+	return counts
+}
+
+// candidateQueue is a min-heap of candidates, ordered by price.
+type candidateQueue []*candidate
+
+func (q candidateQueue) Len() int            { return len(q) }
+func (q candidateQueue) Less(i, j int) bool  { return q[i].totalPricePerMonth < q[j].totalPricePerMonth }
+func (q candidateQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *candidateQueue) Push(x interface{}) { *q = append(*q, x.(*candidate)) }
+
+func (q *candidateQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,52 @@
+package optimizer_test
+
+import (
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/optimizer"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/podgen/podgentest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCheapestMix(t *testing.T) {
+	// A single large node fits all pods, but single node type clusters have at least 2 nodes
+	o := &optimizer.Optimizer{
+		Pods:          podgentest.Pods(t, `pod(cpu: 6, memory: "1Gi") + pod(cpu: 1, memory: "1Gi") * 2`),
+		NodeTypes:     []nodesource.Node{newNodeType("large", "8", "32Gi", 0.5, 0), newNodeType("small", "2", "8Gi", 0.1, 0)},
+		MaxNodeGroups: 2,
+		Top:           2,
+	}
+
+	result, err := o.FindCheapestMix()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"large x1 + small x1", "large x2"}, clusters(o.Results))
+	assert.InDelta(t, 0.6*optimizer.HoursPerMonth, result.TotalPricePerMonth, 1e-9)
+}
+
+func TestFindCheapestMixIsDeterministic(t *testing.T) {
+	nodeTypes := []nodesource.Node{
+		newNodeType("large", "8", "32Gi", 0.5, 0),
+		newNodeType("medium", "4", "16Gi", 0.2, 0),
+		newNodeType("small", "2", "8Gi", 0.1, 0),
+	}
+
+	run := func() *optimizer.Optimizer {
+		o := &optimizer.Optimizer{
+			Pods:          podgentest.Pods(t, `pod(cpu: 3, memory: "2Gi") * 3 + pod(cpu: "500m", memory: "1Gi") * 5`),
+			NodeTypes:     nodeTypes,
+			MaxNodeGroups: 2,
+			Top:           3,
+		}
+
+		_, err := o.FindCheapestMix()
+		assert.Nil(t, err)
+
+		return o
+	}
+
+	first, second := run(), run()
+	assert.NotEmpty(t, first.Results)
+	assert.Equal(t, first.Results, second.Results)
+	assert.Equal(t, first.Candidates, second.Candidates)
+}
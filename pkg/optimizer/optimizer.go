@@ -0,0 +1,363 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/kubesimulator"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// HoursPerMonth is used to convert hourly node prices to monthly prices.
+const HoursPerMonth = nodesource.HoursPerMonth
+
+// NodeGroup is a group of identical nodes in a simulated cluster.
+type NodeGroup struct {
+	NodeType  nodesource.Node
+	NodeCount int
+}
+
+// ReservedNodes returns the number of nodes of the group that are reserved.
+func (g NodeGroup) ReservedNodes() int {
+	reservedNodes := g.NodeType.GetPricing().ReservedNodes
+	if g.NodeCount < reservedNodes {
+		return g.NodeCount
+	}
+
+	return reservedNodes
+}
+
+// Result is a cluster configuration that runs all pods without pending pods.
+type Result struct {
+	NodeGroups         []NodeGroup
+	TotalPricePerMonth float64
+
+	// Nodes and StrandedCapacity are set if the cluster was simulated.
+	Nodes            []kubesimulator.NodePlacement
+	StrandedCapacity v1.ResourceList
+}
+
+// Candidate is a simulated cluster.
+type Candidate struct {
+	NodeGroups         []NodeGroup
+	TotalPricePerMonth float64
+	Successful         bool
+	UnscheduledPods    int
+}
+
+// Rejection is the most expensive cluster of a node type (or a combination of node types in a
+// mixed search) that couldn't run all pods, and the pods that couldn't be scheduled on it.
+type Rejection struct {
+	Result
+	UnscheduledPods []kubesimulator.UnscheduledPod
+	// Diagnosis is why the simulation stopped early, if it did.
+	Diagnosis string
+}
+
+// ErrNoNodeTypes is returned when no node type can run all pods.
+var ErrNoNodeTypes = errors.New("no nodes are available for simulation")
+
+// Optimizer searches for the cheapest cluster that can run a list of pods.
+type Optimizer struct {
+	Pods      []*v1.Pod
+	NodeTypes []nodesource.Node
+
+	// MaxNodeGroups is the maximum number of different node types in a mixed cluster.
+	MaxNodeGroups int
+	// MaxNodesPerGroup limits the size of each node group in a mixed cluster.
+	MaxNodesPerGroup int
+	// MaxTicks is the safety cap on the number of ticks of each simulation.
+	MaxTicks int
+	// Top is the number of cheapest clusters to keep in Results (default: 1).
+	Top int
+
+	// Results are set by the search to the cheapest clusters that run all pods, cheapest first.
+	// There's at most one cluster of each node type (or combination of node types in a mixed search).
+	Results []*Result
+	// Candidates are set by the search, in the order they were simulated.
+	Candidates []Candidate
+	// Rejections are set by the search, in the order their node types were first simulated.
+	Rejections []*Rejection
+	// rejectionsByNodeTypes indexes rejections by the node types of their clusters
+	rejectionsByNodeTypes map[string]*Rejection
+}
+
+// FindCheapest returns the cheapest cluster made of a single node type, or nil if
+// there's no such cluster.
+func (o *Optimizer) FindCheapest() (*Result, error) {
+	// Remove node types if there's a pod with more resources than it
+	filteredNodeTypes := filterNodeTypes(o.NodeTypes, o.Pods)
+	if len(filteredNodeTypes) == 0 {
+		return nil, ErrNoNodeTypes
+	}
+
+	for _, nodeType := range filteredNodeTypes {
+		// We never want a cluster with only 1 node, unless there's only 1 available
+		maxNodes := nodeType.GetCapacity().MaxNodes
+		nodeCount := 2
+		if maxNodes == 1 {
+			nodeCount = 1
+		}
+
+		for {
+			// Calculate total price per month
+			group := NodeGroup{NodeType: nodeType, NodeCount: nodeCount}
+			totalPricePerMonth := PricePerMonth(group)
+
+			// Do we even need to simulate?
+			if maxPrice, ok := o.maxPrice(); ok && totalPricePerMonth > maxPrice {
+				break
+			}
+
+			simulationResult, err := o.simulate(group)
+			if err != nil {
+				return nil, err
+			}
+
+			if simulationResult.Successful {
+				o.addResult(&Result{
+					NodeGroups:         []NodeGroup{group},
+					TotalPricePerMonth: totalPricePerMonth,
+					Nodes:              simulationResult.Nodes,
+					StrandedCapacity:   simulationResult.StrandedCapacity,
+				})
+
+				break
+			}
+
+			if maxNodes > 0 && nodeCount >= maxNodes {
+				break
+			}
+
+			// Simple heuristic as an alternative to nodeCount++ to make convergence faster.
+			nodeCount += int(math.Max(float64(nodeCount)/15, 1))
+			if maxNodes > 0 && nodeCount > maxNodes {
+				nodeCount = maxNodes
+			}
+		}
+	}
+
+	return o.cheapest(), nil
+}
+
+// addResult adds a successful cluster to the ranked results, keeping the Top cheapest ones.
+// Clusters with the same price are ranked in the order they were found.
+func (o *Optimizer) addResult(result *Result) {
+	i := sort.Search(len(o.Results), func(i int) bool {
+		return o.Results[i].TotalPricePerMonth > result.TotalPricePerMonth
+	})
+
+	o.Results = append(o.Results, nil)
+	copy(o.Results[i+1:], o.Results[i:])
+	o.Results[i] = result
+
+	if len(o.Results) > o.top() {
+		o.Results = o.Results[:o.top()]
+	}
+}
+
+// maxPrice returns the price of the most expensive cluster that can still be ranked, if all
+// Top results were already found.
+func (o *Optimizer) maxPrice() (float64, bool) {
+	if len(o.Results) < o.top() {
+		return 0, false
+	}
+
+	return o.Results[len(o.Results)-1].TotalPricePerMonth, true
+}
+
+// cheapest returns the cheapest result, or nil if there's none.
+func (o *Optimizer) cheapest() *Result {
+	if len(o.Results) == 0 {
+		return nil
+	}
+
+	return o.Results[0]
+}
+
+func (o *Optimizer) top() int {
+	if o.Top <= 0 {
+		return 1
+	}
+
+	return o.Top
+}
+
+// simulate runs the pods on a cluster made of the given node groups.
+func (o *Optimizer) simulate(groups ...NodeGroup) (*kubesimulator.SimulationResult, error) {
+	// Generate a list of nodes from the node groups
+	nodes := []nodesource.Node{}
+	for _, group := range groups {
+		for i := 0; i < group.NodeCount; i++ {
+			nodes = append(nodes, group.NodeType)
+		}
+	}
+
+	// Simulate cluster
+	simulator := &kubesimulator.KubernetesSimulator{MaxTicks: o.MaxTicks}
+	simulationResult, err := simulator.Simulate(o.Pods, nodes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to simulate a Kubernetes cluster")
+	}
+
+	o.Candidates = append(o.Candidates, Candidate{
+		NodeGroups:         groups,
+		TotalPricePerMonth: PricePerMonth(groups...),
+		Successful:         simulationResult.Successful,
+		UnscheduledPods:    len(simulationResult.UnscheduledPods),
+	})
+
+	if !simulationResult.Successful {
+		o.reject(groups, simulationResult)
+	}
+
+	return simulationResult, nil
+}
+
+// reject records a cluster that couldn't run all pods. Clusters are simulated from the cheapest
+// to the most expensive for each node type, so later rejections replace earlier ones.
+func (o *Optimizer) reject(groups []NodeGroup, simulationResult *kubesimulator.SimulationResult) {
+	key := ""
+	for _, group := range groups {
+		key += fmt.Sprintf("%p,", group.NodeType)
+	}
+
+	if o.rejectionsByNodeTypes == nil {
+		o.rejectionsByNodeTypes = map[string]*Rejection{}
+	}
+
+	rejection, ok := o.rejectionsByNodeTypes[key]
+	if !ok {
+		rejection = &Rejection{}
+		o.rejectionsByNodeTypes[key] = rejection
+		o.Rejections = append(o.Rejections, rejection)
+	}
+
+	rejection.NodeGroups = groups
+	rejection.TotalPricePerMonth = PricePerMonth(groups...)
+	rejection.UnscheduledPods = simulationResult.UnscheduledPods
+	rejection.Diagnosis = simulationResult.Diagnosis
+}
+
+// PricePerHour returns the total price per hour of the given node groups.
+func PricePerHour(groups ...NodeGroup) float64 {
+	total := 0.0
+	for _, group := range groups {
+		total += group.NodeType.GetGroupHourlyPrice(group.NodeCount)
+	}
+
+	return total
+}
+
+// PricePerMonth returns the total price per month of the given node groups.
+func PricePerMonth(groups ...NodeGroup) float64 {
+	return PricePerHour(groups...) * HoursPerMonth
+}
+
+// OnDemandPricePerHour returns the total price per hour of the given node groups if all of their
+// nodes ran on-demand.
+func OnDemandPricePerHour(groups ...NodeGroup) float64 {
+	total := 0.0
+	for _, group := range groups {
+		pricing := group.NodeType.GetPricing()
+		total += float64(group.NodeCount) * (pricing.OnDemand + pricing.Storage)
+	}
+
+	return total
+}
+
+// SpotPricePerHour returns the total price per hour of the given node groups if all of their
+// nodes ran on spot, or false if the spot price of a node type is unknown.
+func SpotPricePerHour(groups ...NodeGroup) (float64, bool) {
+	total := 0.0
+	for _, group := range groups {
+		pricing := group.NodeType.GetPricing()
+		if pricing.Spot == 0 {
+			return 0, false
+		}
+
+		total += float64(group.NodeCount) * (pricing.Spot + pricing.Storage)
+	}
+
+	return total, true
+}
+
+// StoragePricePerHour returns the price per hour of the volumes of the given node groups.
+func StoragePricePerHour(groups ...NodeGroup) float64 {
+	total := 0.0
+	for _, group := range groups {
+		total += float64(group.NodeCount) * group.NodeType.GetPricing().Storage
+	}
+
+	return total
+}
+
+func filterNodeTypes(nodeTypes []nodesource.Node, pods []*v1.Pod) []nodesource.Node {
+	result := []nodesource.Node{}
+	for _, nodeType := range nodeTypes {
+		nodeHasEnoughResources := true
+
+		for _, pod := range pods {
+			if reason := podFitsNodeType(pod, nodeType); reason != "" {
+				fmt.Fprintf(os.Stderr, "WARNING: Ignoring node type %s %s\n", nodeType.GetName(), reason)
+				nodeHasEnoughResources = false
+				break
+			}
+		}
+
+		if nodeHasEnoughResources {
+			result = append(result, nodeType)
+		}
+	}
+
+	return result
+}
+
+// podFitsNodeType checks whether a pod fits in an empty node of the given type.
+// Returns an empty string if it does, or the reason if it doesn't.
+func podFitsNodeType(pod *v1.Pod, nodeType nodesource.Node) string {
+	nodeConfig := nodeType.GetNodeConfig("node")
+	allocatable := nodeConfig.Status.Allocatable
+	podRequests := kubesimulator.PodRequests(pod)
+
+	// Do the node selector and tolerations of the pod allow it to run on the node?
+	// DaemonSet pods are only created on nodes that they can run on.
+	if reason := kubesimulator.CheckNodeConstraints(pod, nodeConfig); reason != "" {
+		if kubesimulator.IsDaemonSetPod(pod) {
+			return ""
+		}
+
+		return fmt.Sprintf("because there's a pod that can't run on it: %s", reason)
+	}
+
+	// Is Pod CPU > Node CPU?
+	nodeCpu := resource.MustParse(allocatable["cpu"])
+	podCpu := podRequests.Cpu()
+	if podCpu.Cmp(nodeCpu) > 0 {
+		return fmt.Sprintf("with %s CPU because there's a pod with more CPU: %s",
+			nodeCpu.String(), podCpu.String())
+	}
+
+	// Is Pod Memory > Node Memory?
+	nodeMemory := resource.MustParse(allocatable["memory"])
+	podMemory := podRequests.Memory()
+	if podMemory.Cmp(nodeMemory) > 0 {
+		return fmt.Sprintf("with %s memory because there's a pod with more memory: %s",
+			nodeMemory.String(), podMemory.String())
+	}
+
+	// Is Pod GPU > Node GPU?
+	nodeGpu := resource.MustParse(allocatable["nvidia.com/gpu"])
+	podGpu := podRequests["nvidia.com/gpu"]
+	if podGpu.Cmp(nodeGpu) > 0 {
+		return fmt.Sprintf("with %s GPU because there's a pod with more GPU: %s",
+			nodeGpu.String(), podGpu.String())
+	}
+
+	return ""
+}
@@ -0,0 +1,171 @@
+package optimizer_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/optimizer"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/podgen/podgentest"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestPricePerHourReservations(t *testing.T) {
+	section := map[string]interface{}{
+		"region":        "us-east-1",
+		"instanceTypes": []string{"m5.large", "c5.large"},
+		"pricing": map[string]interface{}{
+			"reserved": map[string]interface{}{"nodes": map[string]int{"m5.large": 2}, "term": "1y"},
+		},
+	}
+
+	source, err := nodesource.New("aws", section, nodesource.Options{})
+	assert.Nil(t, err)
+
+	nodes, err := source.GetNodes()
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 2)
+
+	m5, c5 := nodes[0].(*nodesource.AWSNode), nodes[1].(*nodesource.AWSNode)
+	assert.Equal(t, "m5.large", m5.GetName())
+	assert.Equal(t, 2, m5.GetPricing().ReservedNodes)
+	assert.Equal(t, 0, c5.GetPricing().ReservedNodes)
+
+	// Only the m5.large nodes of a mixed cluster are reserved
+	groups := []optimizer.NodeGroup{{NodeType: m5, NodeCount: 3}, {NodeType: c5, NodeCount: 3}}
+	assert.Equal(t, 2, groups[0].ReservedNodes())
+	assert.Equal(t, 0, groups[1].ReservedNodes())
+	assert.InDelta(t, 2*m5.ReservedPrice+m5.OnDemandPrice+3*c5.OnDemandPrice, optimizer.PricePerHour(groups...), 1e-9)
+}
+
+func TestPricePerHourStorage(t *testing.T) {
+	source, err := nodesource.New("aws", map[string]interface{}{
+		"region":        "us-east-1",
+		"instanceTypes": []string{"m5.large"},
+		"volume":        map[string]interface{}{"size": 100},
+		"pricing":       map[string]interface{}{"model": "spot", "spotDiscount": 50},
+	}, nodesource.Options{})
+	assert.Nil(t, err)
+
+	nodes, err := source.GetNodes()
+	assert.Nil(t, err)
+
+	// A 100GB gp3 volume costs $8 per month, which is included in all prices
+	m5 := nodes[0].(*nodesource.AWSNode)
+	storage := 8.0 / optimizer.HoursPerMonth
+	groups := []optimizer.NodeGroup{{NodeType: m5, NodeCount: 3}}
+
+	assert.InDelta(t, 3*storage, optimizer.StoragePricePerHour(groups...), 1e-12)
+	assert.InDelta(t, 3*(m5.OnDemandPrice+storage), optimizer.OnDemandPricePerHour(groups...), 1e-12)
+	spotPricePerHour, ok := optimizer.SpotPricePerHour(groups...)
+	assert.True(t, ok)
+	assert.InDelta(t, 3*(m5.OnDemandPrice/2+storage), spotPricePerHour, 1e-12)
+	assert.InDelta(t, spotPricePerHour, optimizer.PricePerHour(groups...), 1e-12)
+	assert.InDelta(t, 3*(m5.OnDemandPrice/2+storage)*optimizer.HoursPerMonth, optimizer.PricePerMonth(groups...), 1e-9)
+}
+
+// newNodeType returns a node type with the given allocatable resources and price per hour.
+func newNodeType(name string, cpu string, memory string, hourlyCost float64, maxCount int) nodesource.Node {
+	return &nodesource.StaticNode{
+		Name:       name,
+		CPU:        resource.MustParse(cpu),
+		Memory:     resource.MustParse(memory),
+		MaxPods:    110,
+		HourlyCost: hourlyCost,
+		MaxCount:   maxCount,
+	}
+}
+
+// clusters returns the node types and counts of results, e.g "small x4".
+func clusters(results []*optimizer.Result) []string {
+	names := []string{}
+	for _, result := range results {
+		name := ""
+		for i, group := range result.NodeGroups {
+			if i > 0 {
+				name += " + "
+			}
+			name += fmt.Sprintf("%s x%d", group.NodeType.GetName(), group.NodeCount)
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func TestFindCheapest(t *testing.T) {
+	small := newNodeType("small", "2", "8Gi", 0.1, 0)
+	large := newNodeType("large", "8", "32Gi", 0.5, 0)
+
+	tests := []struct {
+		name      string
+		nodeTypes []nodesource.Node
+		top       int
+		expected  []string
+	}{
+		{name: "cheapest node type", nodeTypes: []nodesource.Node{large, small}, expected: []string{"small x4"}},
+		{name: "top clusters cheapest first", nodeTypes: []nodesource.Node{large, small}, top: 2, expected: []string{"small x4", "large x2"}},
+		{name: "max nodes", nodeTypes: []nodesource.Node{large, newNodeType("small", "2", "8Gi", 0.1, 3)}, expected: []string{"large x2"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := &optimizer.Optimizer{
+				Pods:      podgentest.Pods(t, `pod(cpu: 1, memory: "1Gi") * 8`),
+				NodeTypes: test.nodeTypes,
+				Top:       test.top,
+			}
+
+			result, err := o.FindCheapest()
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, clusters(o.Results))
+			assert.Equal(t, o.Results[0], result)
+			assert.InDelta(t, optimizer.PricePerMonth(result.NodeGroups...), result.TotalPricePerMonth, 1e-9)
+
+			// Clusters never have more nodes of a node type than are available
+			for _, candidate := range o.Candidates {
+				for _, group := range candidate.NodeGroups {
+					maxNodes := group.NodeType.GetCapacity().MaxNodes
+					assert.True(t, maxNodes == 0 || group.NodeCount <= maxNodes, "%s x%d", group.NodeType.GetName(), group.NodeCount)
+				}
+			}
+		})
+	}
+}
+
+func TestFindCheapestMaxNodesRejection(t *testing.T) {
+	o := &optimizer.Optimizer{
+		Pods:      podgentest.Pods(t, `pod(cpu: 1, memory: "1Gi") * 8`),
+		NodeTypes: []nodesource.Node{newNodeType("small", "2", "8Gi", 0.1, 3)},
+	}
+
+	result, err := o.FindCheapest()
+	assert.Nil(t, err)
+	assert.Nil(t, result)
+
+	// The largest cluster of the node type is the rejection
+	assert.Len(t, o.Rejections, 1)
+	assert.Equal(t, 3, o.Rejections[0].NodeGroups[0].NodeCount)
+	assert.Len(t, o.Rejections[0].UnscheduledPods, 2)
+}
+
+func TestFindCheapestGKEGPU(t *testing.T) {
+	source, err := nodesource.New("gcp", map[string]interface{}{
+		"region":       "us-central1",
+		"machineTypes": []string{"a2-highgpu-1g", "n2-standard-4"},
+	}, nodesource.Options{})
+	assert.Nil(t, err)
+
+	nodeTypes, err := source.GetNodes()
+	assert.Nil(t, err)
+
+	// GPU nodes are tainted, and pods that request GPUs tolerate the taint
+	o := &optimizer.Optimizer{Pods: podgentest.Pods(t, `pod(cpu: 2, memory: "4Gi", gpu: 1) * 2`), NodeTypes: nodeTypes}
+
+	result, err := o.FindCheapest()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a2-highgpu-1g x2"}, clusters(o.Results))
+	assert.Equal(t, o.Results[0], result)
+}
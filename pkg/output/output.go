@@ -0,0 +1,281 @@
+package output
+
+import (
+	"math"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/kubesimulator"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/optimizer"
+	v1 "k8s.io/api/core/v1"
+)
+
+// monthsPerYear is used to convert monthly prices to yearly prices.
+const monthsPerYear = 12
+
+// Report is the machine-readable output of KubeSurvival. The field names are a documented schema
+// (see the README), so fields may be added but never renamed or removed.
+type Report struct {
+	Region string `json:"region" yaml:"region"`
+
+	// Cheapest is the cheapest cluster that runs all pods, or nil if there's no such cluster.
+	Cheapest *Cluster `json:"cheapest" yaml:"cheapest"`
+	// Ranked are the cheapest clusters that run all pods, cheapest first.
+	Ranked []*Cluster `json:"ranked" yaml:"ranked"`
+
+	// Current and Savings are set if a cluster snapshot is compared.
+	Current *Cluster `json:"current,omitempty" yaml:"current,omitempty"`
+	Savings *Savings `json:"savings,omitempty" yaml:"savings,omitempty"`
+
+	// Candidates are all simulated clusters, in the order they were simulated.
+	Candidates []Candidate `json:"candidates" yaml:"candidates"`
+}
+
+// Cluster is a cluster made of node groups.
+type Cluster struct {
+	NodeGroups []NodeGroup `json:"nodeGroups" yaml:"nodeGroups"`
+	NodeCount  int         `json:"nodeCount" yaml:"nodeCount"`
+	Cost       Cost        `json:"cost" yaml:"cost"`
+
+	// OnDemandCost and SpotCost are the costs if all nodes ran on-demand or on spot. SpotCost is
+	// only set if the spot prices of all node types are known.
+	OnDemandCost Cost  `json:"onDemandCost" yaml:"onDemandCost"`
+	SpotCost     *Cost `json:"spotCost,omitempty" yaml:"spotCost,omitempty"`
+	// StorageCost is the part of the cost that is for volumes, if the nodes have volumes.
+	StorageCost *Cost `json:"storageCost,omitempty" yaml:"storageCost,omitempty"`
+
+	// PodCount and Utilization are only set for simulated clusters.
+	PodCount    int          `json:"podCount,omitempty" yaml:"podCount,omitempty"`
+	Utilization *Utilization `json:"utilization,omitempty" yaml:"utilization,omitempty"`
+}
+
+// NodeGroup is a group of nodes of the same node type.
+type NodeGroup struct {
+	InstanceType string  `json:"instanceType" yaml:"instanceType"`
+	VCPU         int     `json:"vcpu" yaml:"vcpu"`
+	MemoryGiB    float32 `json:"memoryGiB" yaml:"memoryGiB"`
+	GPU          int     `json:"gpu" yaml:"gpu"`
+	MaxPods      int     `json:"maxPods" yaml:"maxPods"`
+	NodeCount    int     `json:"nodeCount" yaml:"nodeCount"`
+	Cost         Cost    `json:"cost" yaml:"cost"`
+
+	// ReservedNodes is the number of nodes that are priced by their reservation.
+	ReservedNodes int `json:"reservedNodes,omitempty" yaml:"reservedNodes,omitempty"`
+	// StorageCost is the part of the cost that is for volumes, if the nodes have volumes.
+	StorageCost *Cost `json:"storageCost,omitempty" yaml:"storageCost,omitempty"`
+
+	// PodCount and Utilization are only set for simulated clusters.
+	PodCount    int          `json:"podCount,omitempty" yaml:"podCount,omitempty"`
+	Utilization *Utilization `json:"utilization,omitempty" yaml:"utilization,omitempty"`
+}
+
+// Cost is the price of nodes in USD. A month is 31 days, and a year is 12 months.
+type Cost struct {
+	Hourly  float64 `json:"hourlyUSD" yaml:"hourlyUSD"`
+	Monthly float64 `json:"monthlyUSD" yaml:"monthlyUSD"`
+	Yearly  float64 `json:"yearlyUSD" yaml:"yearlyUSD"`
+}
+
+// Utilization is the percentage of the allocatable resources of nodes that is requested by pods.
+type Utilization struct {
+	CPU    float64 `json:"cpuPercent" yaml:"cpuPercent"`
+	Memory float64 `json:"memoryPercent" yaml:"memoryPercent"`
+	GPU    float64 `json:"gpuPercent" yaml:"gpuPercent"`
+	Pods   float64 `json:"podsPercent" yaml:"podsPercent"`
+}
+
+// Savings is how much cheaper the cheapest cluster is than the current cluster.
+type Savings struct {
+	Monthly float64 `json:"monthlyUSD" yaml:"monthlyUSD"`
+	Yearly  float64 `json:"yearlyUSD" yaml:"yearlyUSD"`
+	Percent float64 `json:"percent" yaml:"percent"`
+}
+
+// Candidate is a simulated cluster.
+type Candidate struct {
+	NodeGroups      []CandidateNodeGroup `json:"nodeGroups" yaml:"nodeGroups"`
+	Cost            Cost                 `json:"cost" yaml:"cost"`
+	Successful      bool                 `json:"successful" yaml:"successful"`
+	UnscheduledPods int                  `json:"unscheduledPods" yaml:"unscheduledPods"`
+}
+
+// CandidateNodeGroup is a node group of a candidate.
+type CandidateNodeGroup struct {
+	InstanceType string `json:"instanceType" yaml:"instanceType"`
+	NodeCount    int    `json:"nodeCount" yaml:"nodeCount"`
+	Cost         Cost   `json:"cost" yaml:"cost"`
+}
+
+// NewReport creates a report of a search from its ranked results, cheapest first. The current
+// cluster may be nil.
+func NewReport(region string, results []*optimizer.Result, current *optimizer.Result,
+	candidates []optimizer.Candidate) *Report {
+
+	report := &Report{
+		Region:     region,
+		Ranked:     []*Cluster{},
+		Current:    newCluster(current),
+		Candidates: []Candidate{},
+	}
+
+	var cheapest *optimizer.Result
+	if len(results) > 0 {
+		cheapest = results[0]
+		report.Cheapest = newCluster(cheapest)
+	}
+
+	for _, result := range results {
+		report.Ranked = append(report.Ranked, newCluster(result))
+	}
+
+	if cheapest != nil && current != nil {
+		savings := current.TotalPricePerMonth - cheapest.TotalPricePerMonth
+		percent := 0.0
+		if current.TotalPricePerMonth > 0 {
+			percent = savings / current.TotalPricePerMonth * 100
+		}
+
+		report.Savings = &Savings{
+			Monthly: round(savings, 2),
+			Yearly:  round(savings*monthsPerYear, 2),
+			Percent: round(percent, 1),
+		}
+	}
+
+	for _, candidate := range candidates {
+		nodeGroups := []CandidateNodeGroup{}
+		for _, group := range candidate.NodeGroups {
+			nodeGroups = append(nodeGroups, CandidateNodeGroup{
+				InstanceType: group.NodeType.GetName(),
+				NodeCount:    group.NodeCount,
+				Cost:         newCost(optimizer.PricePerHour(group)),
+			})
+		}
+
+		report.Candidates = append(report.Candidates, Candidate{
+			NodeGroups:      nodeGroups,
+			Cost:            newCost(optimizer.PricePerHour(candidate.NodeGroups...)),
+			Successful:      candidate.Successful,
+			UnscheduledPods: candidate.UnscheduledPods,
+		})
+	}
+
+	return report
+}
+
+// newCluster converts a result to a cluster, or returns nil if there's no result.
+func newCluster(result *optimizer.Result) *Cluster {
+	if result == nil {
+		return nil
+	}
+
+	cluster := &Cluster{
+		NodeGroups:   []NodeGroup{},
+		Cost:         newCost(optimizer.PricePerHour(result.NodeGroups...)),
+		OnDemandCost: newCost(optimizer.OnDemandPricePerHour(result.NodeGroups...)),
+	}
+
+	if spotPricePerHour, ok := optimizer.SpotPricePerHour(result.NodeGroups...); ok {
+		spotCost := newCost(spotPricePerHour)
+		cluster.SpotCost = &spotCost
+	}
+	cluster.StorageCost = newStorageCost(result.NodeGroups...)
+
+	// Nodes of simulated clusters are in the order of their node groups
+	nodes := result.Nodes
+	for _, group := range result.NodeGroups {
+		nodeGroup := NodeGroup{
+			InstanceType:  group.NodeType.GetName(),
+			VCPU:          group.NodeType.GetCapacity().VCPU,
+			MemoryGiB:     group.NodeType.GetCapacity().MemoryGiB,
+			GPU:           group.NodeType.GetCapacity().GPU,
+			MaxPods:       group.NodeType.GetCapacity().MaxPods,
+			NodeCount:     group.NodeCount,
+			Cost:          newCost(optimizer.PricePerHour(group)),
+			ReservedNodes: group.ReservedNodes(),
+			StorageCost:   newStorageCost(group),
+		}
+
+		if len(nodes) >= group.NodeCount {
+			nodeGroup.PodCount = podCount(nodes[:group.NodeCount])
+			nodeGroup.Utilization = newUtilization(nodes[:group.NodeCount])
+			nodes = nodes[group.NodeCount:]
+		}
+
+		cluster.NodeGroups = append(cluster.NodeGroups, nodeGroup)
+		cluster.NodeCount += group.NodeCount
+	}
+
+	if len(result.Nodes) > 0 {
+		cluster.PodCount = podCount(result.Nodes)
+		cluster.Utilization = newUtilization(result.Nodes)
+	}
+
+	return cluster
+}
+
+// newCost returns the cost of nodes from their price per hour.
+func newCost(hourly float64) Cost {
+	monthly := hourly * optimizer.HoursPerMonth
+
+	return Cost{
+		Hourly:  round(hourly, 4),
+		Monthly: round(monthly, 2),
+		Yearly:  round(monthly*monthsPerYear, 2),
+	}
+}
+
+// newStorageCost returns the cost of the volumes of node groups, or nil if they don't have volumes.
+func newStorageCost(groups ...optimizer.NodeGroup) *Cost {
+	storagePricePerHour := optimizer.StoragePricePerHour(groups...)
+	if storagePricePerHour == 0 {
+		return nil
+	}
+
+	storageCost := newCost(storagePricePerHour)
+	return &storageCost
+}
+
+// podCount returns the number of pods on the nodes.
+func podCount(nodes []kubesimulator.NodePlacement) int {
+	count := 0
+	for _, node := range nodes {
+		count += len(node.Pods)
+	}
+
+	return count
+}
+
+// newUtilization returns the utilization of the nodes.
+func newUtilization(nodes []kubesimulator.NodePlacement) *Utilization {
+	return &Utilization{
+		CPU:    utilization(nodes, v1.ResourceCPU),
+		Memory: utilization(nodes, v1.ResourceMemory),
+		GPU:    utilization(nodes, "nvidia.com/gpu"),
+		Pods:   utilization(nodes, v1.ResourcePods),
+	}
+}
+
+// utilization returns the percentage of a resource of the nodes that is allocated, or 0 if the
+// nodes don't have the resource.
+func utilization(nodes []kubesimulator.NodePlacement, name v1.ResourceName) float64 {
+	allocated, allocatable := int64(0), int64(0)
+	for _, node := range nodes {
+		if quantity, ok := node.Allocated[name]; ok {
+			allocated += quantity.MilliValue()
+		}
+		if quantity, ok := node.Allocatable[name]; ok {
+			allocatable += quantity.MilliValue()
+		}
+	}
+
+	if allocatable == 0 {
+		return 0
+	}
+
+	return round(float64(allocated)/float64(allocatable)*100, 1)
+}
+
+// round rounds a number to the given number of decimal places.
+func round(x float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(x*scale) / scale
+}
@@ -0,0 +1,120 @@
+package output_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/kubesimulator"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/nodesource"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/optimizer"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/output"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func testReport() *output.Report {
+	nodeType := &nodesource.AWSNode{InstanceType: "m5.large", OnDemandPrice: 0.1, VCPU: 2, Memory: 8, MaxPods: 29}
+	groups := []optimizer.NodeGroup{{NodeType: nodeType, NodeCount: 2}}
+
+	node := kubesimulator.NodePlacement{
+		Name:         "node-1",
+		InstanceType: "m5.large",
+		Allocatable: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("2"),
+			v1.ResourceMemory: resource.MustParse("8Gi"),
+			v1.ResourcePods:   resource.MustParse("29"),
+		},
+		Allocated: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("1"),
+			v1.ResourceMemory: resource.MustParse("2Gi"),
+			v1.ResourcePods:   resource.MustParse("1"),
+		},
+		Pods: []string{"default/api-1"},
+	}
+
+	cheapest := &optimizer.Result{
+		NodeGroups:         groups,
+		TotalPricePerMonth: optimizer.PricePerMonth(groups...),
+		Nodes:              []kubesimulator.NodePlacement{node, node},
+	}
+
+	candidates := []optimizer.Candidate{
+		{NodeGroups: []optimizer.NodeGroup{{NodeType: nodeType, NodeCount: 1}}, UnscheduledPods: 1},
+		{NodeGroups: groups, Successful: true},
+	}
+
+	return output.NewReport("us-east-1", []*optimizer.Result{cheapest}, nil, candidates)
+}
+
+func TestWriteJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := output.Write(out, "json", testReport())
+	assert.Nil(t, err)
+
+	report := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &report))
+
+	assert.Equal(t, "us-east-1", report["region"])
+	assert.Nil(t, report["current"])
+
+	cheapest := report["cheapest"].(map[string]interface{})
+	assert.Equal(t, 2.0, cheapest["nodeCount"])
+	assert.Equal(t, 2.0, cheapest["podCount"])
+	assert.Equal(t, map[string]interface{}{"hourlyUSD": 0.2, "monthlyUSD": 148.8, "yearlyUSD": 1785.6}, cheapest["cost"])
+	assert.Equal(t, cheapest["cost"], cheapest["onDemandCost"])
+	assert.NotContains(t, cheapest, "spotCost")
+	assert.Equal(t, map[string]interface{}{
+		"cpuPercent": 50.0, "memoryPercent": 25.0, "gpuPercent": 0.0, "podsPercent": 3.4,
+	}, cheapest["utilization"])
+
+	assert.Equal(t, []interface{}{cheapest}, report["ranked"])
+
+	candidates := report["candidates"].([]interface{})
+	assert.Len(t, candidates, 2)
+	assert.Equal(t, false, candidates[0].(map[string]interface{})["successful"])
+	assert.Equal(t, 1.0, candidates[0].(map[string]interface{})["unscheduledPods"])
+}
+
+func TestWriteCSV(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := output.Write(out, "csv", testReport())
+	assert.Nil(t, err)
+
+	assert.Equal(t, `cluster,successful,instance_type,node_count,hourly_usd,monthly_usd,yearly_usd,pod_count,cpu_percent,memory_percent,gpu_percent,pods_percent,rank
+cheapest,true,m5.large,2,0.2,148.8,1785.6,2,50,25,0,3.4,1
+candidate-1,false,m5.large,1,0.1,74.4,892.8,,,,,,
+candidate-2,true,m5.large,2,0.2,148.8,1785.6,,,,,,
+`, out.String())
+}
+
+func TestWriteCSVRanked(t *testing.T) {
+	small := &nodesource.AWSNode{InstanceType: "m5.large", OnDemandPrice: 0.1, VCPU: 2, Memory: 8, MaxPods: 29}
+	large := &nodesource.AWSNode{InstanceType: "m5.xlarge", OnDemandPrice: 0.2, VCPU: 4, Memory: 16, MaxPods: 58}
+
+	results := []*optimizer.Result{}
+	for _, groups := range [][]optimizer.NodeGroup{
+		{{NodeType: small, NodeCount: 2}},
+		{{NodeType: large, NodeCount: 2}},
+		{{NodeType: small, NodeCount: 1}, {NodeType: large, NodeCount: 2}},
+	} {
+		results = append(results, &optimizer.Result{NodeGroups: groups, TotalPricePerMonth: optimizer.PricePerMonth(groups...)})
+	}
+
+	out := &bytes.Buffer{}
+	err := output.Write(out, "csv", output.NewReport("us-east-1", results, nil, []optimizer.Candidate{}))
+	assert.Nil(t, err)
+
+	assert.Equal(t, `cluster,successful,instance_type,node_count,hourly_usd,monthly_usd,yearly_usd,pod_count,cpu_percent,memory_percent,gpu_percent,pods_percent,rank
+cheapest,true,m5.large,2,0.2,148.8,1785.6,,,,,,1
+ranked,true,m5.xlarge,2,0.4,297.6,3571.2,,,,,,2
+ranked,true,m5.large,1,0.1,74.4,892.8,,,,,,3
+ranked,true,m5.xlarge,2,0.4,297.6,3571.2,,,,,,3
+`, out.String())
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	err := output.Write(&bytes.Buffer{}, "xml", testReport())
+	assert.EqualError(t, err, "unknown output format xml")
+}
@@ -0,0 +1,110 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// csvHeader are the columns of the CSV format. Every row is a node group of a cluster.
+var csvHeader = []string{
+	"cluster", "successful", "instance_type", "node_count", "hourly_usd", "monthly_usd", "yearly_usd",
+	"pod_count", "cpu_percent", "memory_percent", "gpu_percent", "pods_percent", "rank",
+}
+
+// Write writes the report in a format: json, yaml or csv.
+func Write(w io.Writer, format string, report *Report) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return errors.Wrap(encoder.Encode(report), "could not write JSON")
+
+	case "yaml":
+		out, err := yaml.Marshal(report)
+		if err != nil {
+			return errors.Wrap(err, "could not serialize YAML")
+		}
+
+		_, err = w.Write(out)
+		return errors.Wrap(err, "could not write YAML")
+
+	case "csv":
+		return writeCSV(w, report)
+
+	default:
+		return errors.Errorf("unknown output format %s", format)
+	}
+}
+
+// writeCSV writes the node groups of the cheapest cluster, the other ranked clusters, the current
+// cluster and the candidates, which are named candidate-1, candidate-2, etc. The cheapest cluster
+// has rank 1, and the other ranked clusters have their rank.
+func writeCSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{csvHeader}
+
+	if report.Cheapest != nil {
+		rows = append(rows, clusterRows("cheapest", "true", "1", report.Cheapest)...)
+	}
+	if len(report.Ranked) > 1 {
+		for i, cluster := range report.Ranked[1:] {
+			rows = append(rows, clusterRows("ranked", "true", strconv.Itoa(i+2), cluster)...)
+		}
+	}
+	if report.Current != nil {
+		rows = append(rows, clusterRows("current", "", "", report.Current)...)
+	}
+
+	for i, candidate := range report.Candidates {
+		for _, group := range candidate.NodeGroups {
+			rows = append(rows, []string{
+				fmt.Sprintf("candidate-%d", i+1), strconv.FormatBool(candidate.Successful),
+				group.InstanceType, strconv.Itoa(group.NodeCount),
+				formatFloat(group.Cost.Hourly), formatFloat(group.Cost.Monthly), formatFloat(group.Cost.Yearly),
+				"", "", "", "", "", "",
+			})
+		}
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return errors.Wrap(err, "could not write CSV")
+	}
+
+	return nil
+}
+
+// clusterRows returns a CSV row for each node group of a cluster.
+func clusterRows(name string, successful string, rank string, cluster *Cluster) [][]string {
+	rows := [][]string{}
+	for _, group := range cluster.NodeGroups {
+		row := []string{
+			name, successful, group.InstanceType, strconv.Itoa(group.NodeCount),
+			formatFloat(group.Cost.Hourly), formatFloat(group.Cost.Monthly), formatFloat(group.Cost.Yearly),
+		}
+
+		if group.Utilization != nil {
+			row = append(row,
+				strconv.Itoa(group.PodCount),
+				formatFloat(group.Utilization.CPU), formatFloat(group.Utilization.Memory),
+				formatFloat(group.Utilization.GPU), formatFloat(group.Utilization.Pods),
+			)
+		} else {
+			row = append(row, "", "", "", "", "")
+		}
+
+		rows = append(rows, append(row, rank))
+	}
+
+	return rows
+}
+
+// formatFloat formats a number with as few digits as needed.
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'f', -1, 64)
+}
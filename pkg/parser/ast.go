@@ -39,6 +39,44 @@ type Expression interface {
 	expression()
 }
 
+// Statement is a node that doesn't evaluate to a value, such as a let binding.
+type Statement interface {
+	Node
+	// statement is unexported to ensure implementations of Statement
+	// can only originate in this package.
+	statement()
+}
+
+// Program is the root node of the abstract syntax tree. It contains a list of statements,
+// followed by the expression that describes the workload.
+type Program struct {
+	Statements []Statement
+	Expression Expression
+}
+
+// LetStatement is a statement that binds a name to an expression, e.g let api = pod(cpu: 1).
+type LetStatement struct {
+	Name     string
+	Value    Expression
+	Position lexer.Position
+}
+
+// DefStatement is a statement that defines a pod template with parameters,
+// e.g def svc(cpu, replicas) = pod(cpu: cpu) * replicas.
+type DefStatement struct {
+	Name       string
+	Parameters []*Identifier
+	Body       Expression
+	Position   lexer.Position
+}
+
+// Identifier is an expression that refers to a name bound by a let statement or to
+// a template parameter.
+type Identifier struct {
+	Name     string
+	Position lexer.Position
+}
+
 // IntLiteral is an expression that contains a single constant integer number.
 type IntLiteral struct {
 	Value    int64
@@ -59,20 +97,71 @@ type ArithmeticExpression struct {
 	Position lexer.Position
 }
 
-// PodExpression is an expression that represents a pod.
+// CallExpression is an expression that instantiates a pod template, e.g svc("250m", 3).
+type CallExpression struct {
+	Name      string
+	Arguments []Expression
+	Position  lexer.Position
+}
+
+// PodExpression is an expression that represents a pod. The CPU, memory and GPU of a pod
+// are a shorthand for a pod with a single container.
 type PodExpression struct {
+	CPU            Expression
+	Memory         Expression
+	GPU            Expression
+	Containers     []Expression
+	InitContainers []Expression
+	NodeSelector   []*MapEntry
+	Tolerations    []Expression
+	Position       lexer.Position
+}
+
+// SpreadExpression is an expression that spreads the pods of an expression across nodes
+// or zones, e.g spread(api * 3, by: "zone", maxSkew: 1).
+type SpreadExpression struct {
+	Expression Expression
+	By         Expression
+	MaxSkew    Expression
+	Position   lexer.Position
+}
+
+// MapEntry is a key-value pair in a map, e.g "gpu": "true" in a node selector.
+type MapEntry struct {
+	Key      string
+	Value    Expression
+	Position lexer.Position
+}
+
+// ContainerExpression is an expression that represents a container of a pod.
+type ContainerExpression struct {
 	CPU      Expression
 	Memory   Expression
 	GPU      Expression
 	Position lexer.Position
 }
 
+func (*Program) node()              {}
+func (*LetStatement) node()         {}
+func (*DefStatement) node()         {}
+func (*Identifier) node()           {}
 func (*IntLiteral) node()           {}
 func (*StringLiteral) node()        {}
 func (*ArithmeticExpression) node() {}
+func (*CallExpression) node()       {}
 func (*PodExpression) node()        {}
+func (*ContainerExpression) node()  {}
+func (*MapEntry) node()             {}
+func (*SpreadExpression) node()     {}
+
+func (*LetStatement) statement() {}
+func (*DefStatement) statement() {}
 
+func (*Identifier) expression()           {}
 func (*IntLiteral) expression()           {}
 func (*StringLiteral) expression()        {}
 func (*ArithmeticExpression) expression() {}
+func (*CallExpression) expression()       {}
 func (*PodExpression) expression()        {}
+func (*ContainerExpression) expression()  {}
+func (*SpreadExpression) expression()     {}
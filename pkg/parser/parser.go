@@ -23,10 +23,10 @@ func NewParser(scanner *lexer.Scanner) *Parser {
 	}
 }
 
-// Parse parses an expression and returns its AST representation.
-func Parse(s string) (Expression, []ParseError) {
+// Parse parses a program and returns its AST representation.
+func Parse(s string) (*Program, []ParseError) {
 	parser := NewParser(lexer.NewScanner(strings.NewReader(s)))
-	return parser.ParseExpression(), parser.Errors
+	return parser.ParseProgram(), parser.Errors
 }
 
 func (p *Parser) matchToken(tokenTypes ...lexer.TokenType) (*lexer.Token, bool) {
@@ -54,9 +54,117 @@ func (p *Parser) skip() {
 	p.lookahead = p.scanner.Scan()
 }
 
+// ParseProgram parses a list of statements followed by an expression.
+func (p *Parser) ParseProgram() *Program {
+	program := &Program{Statements: []Statement{}}
+
+	for {
+		switch p.lookahead.TokenType {
+		case lexer.LET:
+			program.Statements = append(program.Statements, p.ParseLetStatement())
+			continue
+
+		case lexer.DEF:
+			program.Statements = append(program.Statements, p.ParseDefStatement())
+			continue
+		}
+
+		break
+	}
+
+	program.Expression = p.ParseExpression()
+
+	if p.lookahead.TokenType != lexer.EOF {
+		p.addError(newParseError(p.lookahead.Lexeme, []string{"+", "*", "EOF"}, p.lookahead.Position))
+	}
+
+	return program
+}
+
+// ParseLetStatement parses a let statement, e.g let api = pod(cpu: 1).
+func (p *Parser) ParseLetStatement() Statement {
+	// let
+	letToken, ok := p.match(lexer.LET)
+	if !ok {
+		p.addError(newParseError(letToken.Lexeme, []string{"let"}, letToken.Position))
+	}
+
+	statement := &LetStatement{Position: letToken.Position}
+
+	// name
+	if token, ok := p.match(lexer.IDENT); ok {
+		statement.Name = token.Lexeme
+	} else {
+		p.addError(newParseError(token.Lexeme, []string{"IDENT"}, token.Position))
+	}
+
+	// =
+	if token, ok := p.match(lexer.ASSIGN); !ok {
+		p.addError(newParseError(token.Lexeme, []string{"="}, token.Position))
+	}
+
+	statement.Value = p.ParseExpression()
+
+	return statement
+}
+
+// ParseDefStatement parses a template definition, e.g def svc(cpu, replicas) = pod(cpu: cpu) * replicas.
+func (p *Parser) ParseDefStatement() Statement {
+	// def
+	defToken, ok := p.match(lexer.DEF)
+	if !ok {
+		p.addError(newParseError(defToken.Lexeme, []string{"def"}, defToken.Position))
+	}
+
+	statement := &DefStatement{Position: defToken.Position, Parameters: []*Identifier{}}
+
+	// name
+	if token, ok := p.match(lexer.IDENT); ok {
+		statement.Name = token.Lexeme
+	} else {
+		p.addError(newParseError(token.Lexeme, []string{"IDENT"}, token.Position))
+	}
+
+	// (
+	if token, ok := p.match(lexer.LPAREN); !ok {
+		p.addError(newParseError(token.Lexeme, []string{"("}, token.Position))
+	}
+
+	// Parameters
+	if _, ok := p.match(lexer.RPAREN); !ok {
+		for {
+			statement.Parameters = append(statement.Parameters, p.ParseIdentifier())
+
+			if _, ok := p.match(lexer.COMMA); ok {
+				continue
+			}
+
+			if token, ok := p.match(lexer.RPAREN); !ok {
+				p.addError(newParseError(token.Lexeme, []string{",", ")"}, token.Position))
+			}
+
+			break
+		}
+	}
+
+	// =
+	if token, ok := p.match(lexer.ASSIGN); !ok {
+		p.addError(newParseError(token.Lexeme, []string{"="}, token.Position))
+	}
+
+	statement.Body = p.ParseExpression()
+
+	return statement
+}
+
 // ParseExpression parses expressions that might contain any arthimatic operator.
 func (p *Parser) ParseExpression() Expression {
-	result := p.ParseTerm()
+	return p.parseAdditions(p.ParseTerm())
+}
+
+// parseAdditions parses the additions that follow the first term of an expression.
+func (p *Parser) parseAdditions(lhs Expression) Expression {
+	result := lhs
 	for p.lookahead.TokenType == lexer.ADD {
 		position := p.lookahead.Position
 		p.match(lexer.ADD)
@@ -75,27 +183,42 @@ func (p *Parser) ParseExpression() Expression {
 
 // ParseTerm parses expressions that might contain multipications.
 func (p *Parser) ParseTerm() Expression {
-	var result Expression
-
-	var isLHSInteger = (p.lookahead.TokenType == lexer.INTEGER)
-	if isLHSInteger {
-		result = p.ParseInteger()
+	if p.lookahead.TokenType == lexer.INTEGER {
+		result := p.ParseInteger()
 		if p.lookahead.TokenType != lexer.MUL {
 			p.addError(newParseError(p.lookahead.Lexeme, []string{"*"}, p.lookahead.Position))
 		}
-	} else {
-		result = p.ParseFactor()
+
+		return p.parseMultiplications(result)
 	}
 
+	return p.parseMultiplications(p.ParseFactor())
+}
+
+// parseMultiplications parses the multiplications that follow the first factor of a term.
+func (p *Parser) parseMultiplications(lhs Expression) Expression {
+	result := lhs
 	for p.lookahead.TokenType == lexer.MUL {
 		position := p.lookahead.Position
 		p.match(lexer.MUL)
 
 		var rhs Expression
-		if isLHSInteger {
+		switch lhs.(type) {
+		case *IntLiteral:
+			// e.g 3 * pod()
 			rhs = p.ParseFactor()
-		} else {
-			rhs = p.ParseInteger()
+
+		case *Identifier:
+			// Either replicas * pod() or api * 3, which is checked during podgen.
+			if p.lookahead.TokenType == lexer.INTEGER {
+				rhs = p.ParseInteger()
+			} else {
+				rhs = p.ParseFactor()
+			}
+
+		default:
+			// e.g pod() * 3 or pod() * replicas
+			rhs = p.ParseIntegerOrIdentifier()
 		}
 
 		result = &ArithmeticExpression{
@@ -126,13 +249,86 @@ func (p *Parser) ParseFactor() Expression {
 	case lexer.POD:
 		return p.ParsePod()
 
+	case lexer.CONTAINER:
+		// e.g let sidecar = container(cpu: "100m")
+		return p.ParseContainer()
+
+	case lexer.SPREAD:
+		return p.ParseSpread()
+
+	case lexer.IDENT:
+		identifier := p.ParseIdentifier()
+		if p.lookahead.TokenType == lexer.LPAREN {
+			return p.ParseCall(identifier)
+		}
+
+		return identifier
+
+	case lexer.CPU, lexer.MEMORY, lexer.GPU:
+		return p.ParseIdentifier()
+
 	default:
-		p.addError(newParseError(p.lookahead.Lexeme, []string{"(", "pod"},
+		p.addError(newParseError(p.lookahead.Lexeme, []string{"(", "pod", "IDENT"},
 			p.lookahead.Position))
 		return nil
 	}
 }
 
+// ParseIdentifier parses a name. Keywords that are only meaningful inside a pod
+// (cpu, memory, gpu) can be used as names as well.
+func (p *Parser) ParseIdentifier() *Identifier {
+	token, ok := p.match(lexer.IDENT, lexer.CPU, lexer.MEMORY, lexer.GPU)
+	if !ok {
+		p.addError(newParseError(token.Lexeme, []string{"IDENT"}, token.Position))
+	}
+
+	return &Identifier{Position: token.Position, Name: token.Lexeme}
+}
+
+// ParseCall parses the arguments of a template call, e.g svc("250m", 3).
+func (p *Parser) ParseCall(name *Identifier) Expression {
+	call := &CallExpression{Position: name.Position, Name: name.Name, Arguments: []Expression{}}
+
+	// (
+	if token, ok := p.match(lexer.LPAREN); !ok {
+		p.addError(newParseError(token.Lexeme, []string{"("}, token.Position))
+	}
+
+	if _, ok := p.match(lexer.RPAREN); ok {
+		return call
+	}
+
+	for {
+		call.Arguments = append(call.Arguments, p.ParseArgument())
+
+		if _, ok := p.match(lexer.COMMA); ok {
+			continue
+		}
+
+		if token, ok := p.match(lexer.RPAREN); !ok {
+			p.addError(newParseError(token.Lexeme, []string{",", ")"}, token.Position))
+		}
+
+		return call
+	}
+}
+
+// ParseArgument parses a template call argument, which is either a string, an integer
+// or an expression.
+func (p *Parser) ParseArgument() Expression {
+	switch p.lookahead.TokenType {
+	case lexer.STRING:
+		return p.ParseString()
+
+	case lexer.INTEGER:
+		// Either a plain integer, or the beginning of an expression, e.g 3 * pod()
+		return p.parseAdditions(p.parseMultiplications(p.ParseInteger()))
+
+	default:
+		return p.ParseExpression()
+	}
+}
+
 func (p *Parser) ParsePod() Expression {
 	// pod
 	podToken, ok := p.match(lexer.POD)
@@ -173,8 +369,41 @@ func (p *Parser) ParsePod() Expression {
 
 			pod.GPU = p.ParseStringOrInteger()
 
+		case lexer.CONTAINERS:
+			p.match(lexer.CONTAINERS)
+			if token, ok := p.match(lexer.COLON); !ok {
+				p.addError(newParseError(token.Lexeme, []string{":"}, token.Position))
+			}
+
+			pod.Containers = p.ParseContainerList()
+
+		case lexer.INIT:
+			p.match(lexer.INIT)
+			if token, ok := p.match(lexer.COLON); !ok {
+				p.addError(newParseError(token.Lexeme, []string{":"}, token.Position))
+			}
+
+			pod.InitContainers = p.ParseContainerList()
+
+		case lexer.NODESELECTOR:
+			p.match(lexer.NODESELECTOR)
+			if token, ok := p.match(lexer.COLON); !ok {
+				p.addError(newParseError(token.Lexeme, []string{":"}, token.Position))
+			}
+
+			pod.NodeSelector = p.ParseMap()
+
+		case lexer.TOLERATIONS:
+			p.match(lexer.TOLERATIONS)
+			if token, ok := p.match(lexer.COLON); !ok {
+				p.addError(newParseError(token.Lexeme, []string{":"}, token.Position))
+			}
+
+			pod.Tolerations = p.ParseStringList()
+
 		default:
-			p.addError(newParseError(p.lookahead.Lexeme, []string{"cpu", "memory", "gpu", ")"},
+			p.addError(newParseError(p.lookahead.Lexeme,
+				[]string{"cpu", "memory", "gpu", "containers", "init", "nodeSelector", "tolerations", ")"},
 				p.lookahead.Position))
 			return pod
 		}
@@ -195,9 +424,235 @@ func (p *Parser) ParsePod() Expression {
 		}
 	}
 
+	// Empty pod, e.g pod()
+	p.match(lexer.RPAREN)
+
 	return pod
 }
 
+// ParseSpread parses a spread expression, e.g spread(api * 3, by: "zone", maxSkew: 1).
+func (p *Parser) ParseSpread() Expression {
+	// spread
+	spreadToken, ok := p.match(lexer.SPREAD)
+	if !ok {
+		p.addError(newParseError(spreadToken.Lexeme, []string{"spread"}, spreadToken.Position))
+	}
+
+	// (
+	if token, ok := p.match(lexer.LPAREN); !ok {
+		p.addError(newParseError(token.Lexeme, []string{"("}, token.Position))
+	}
+
+	spread := &SpreadExpression{Position: spreadToken.Position}
+	spread.Expression = p.ParseExpression()
+
+	for p.lookahead.TokenType == lexer.COMMA {
+		p.match(lexer.COMMA)
+
+		field, ok := p.match(lexer.BY, lexer.MAXSKEW)
+		if !ok {
+			p.addError(newParseError(field.Lexeme, []string{"by", "maxSkew"}, field.Position))
+			return spread
+		}
+
+		if token, ok := p.match(lexer.COLON); !ok {
+			p.addError(newParseError(token.Lexeme, []string{":"}, token.Position))
+		}
+
+		switch field.TokenType {
+		case lexer.BY:
+			spread.By = p.ParseStringOrInteger()
+		case lexer.MAXSKEW:
+			spread.MaxSkew = p.ParseIntegerOrIdentifier()
+		}
+	}
+
+	// )
+	if token, ok := p.match(lexer.RPAREN); !ok {
+		p.addError(newParseError(token.Lexeme, []string{",", ")"}, token.Position))
+	}
+
+	return spread
+}
+
+// ParseContainerList parses a list of containers, e.g [container(cpu: 1), sidecar].
+func (p *Parser) ParseContainerList() []Expression {
+	return p.parseList(func() Expression {
+		switch p.lookahead.TokenType {
+		case lexer.CONTAINER:
+			return p.ParseContainer()
+
+		case lexer.IDENT, lexer.CPU, lexer.MEMORY, lexer.GPU:
+			// e.g a container bound by a let statement
+			return p.ParseIdentifier()
+
+		default:
+			p.addError(newParseError(p.lookahead.Lexeme, []string{"container", "IDENT", "]"},
+				p.lookahead.Position))
+			return nil
+		}
+	})
+}
+
+// ParseStringList parses a list of strings, e.g ["gpu=true:NoSchedule", dedicated].
+func (p *Parser) ParseStringList() []Expression {
+	return p.parseList(func() Expression {
+		switch p.lookahead.TokenType {
+		case lexer.STRING:
+			return p.ParseString()
+
+		case lexer.IDENT, lexer.CPU, lexer.MEMORY, lexer.GPU:
+			return p.ParseIdentifier()
+
+		default:
+			p.addError(newParseError(p.lookahead.Lexeme, []string{"STRING", "IDENT", "]"},
+				p.lookahead.Position))
+			return nil
+		}
+	})
+}
+
+// parseList parses a comma separated list in brackets. Stops at the first element that
+// can't be parsed, in which case parseElement returns nil.
+func (p *Parser) parseList(parseElement func() Expression) []Expression {
+	elements := []Expression{}
+
+	// [
+	if token, ok := p.match(lexer.LBRACKET); !ok {
+		p.addError(newParseError(token.Lexeme, []string{"["}, token.Position))
+		return elements
+	}
+
+	for p.lookahead.TokenType != lexer.RBRACKET {
+		element := parseElement()
+		if element == nil {
+			return elements
+		}
+
+		elements = append(elements, element)
+
+		switch p.lookahead.TokenType {
+		case lexer.RBRACKET:
+			// Handled by the loop condition
+
+		case lexer.COMMA:
+			p.match(lexer.COMMA)
+
+		default:
+			p.addError(newParseError(p.lookahead.Lexeme, []string{",", "]"},
+				p.lookahead.Position))
+			return elements
+		}
+	}
+
+	// ]
+	p.match(lexer.RBRACKET)
+
+	return elements
+}
+
+// ParseMap parses a map with string keys in braces, e.g {"gpu": "true", "zone": zone}.
+func (p *Parser) ParseMap() []*MapEntry {
+	entries := []*MapEntry{}
+
+	// {
+	if token, ok := p.match(lexer.LBRACE); !ok {
+		p.addError(newParseError(token.Lexeme, []string{"{"}, token.Position))
+		return entries
+	}
+
+	for p.lookahead.TokenType != lexer.RBRACE {
+		key, ok := p.match(lexer.STRING)
+		if !ok {
+			p.addError(newParseError(key.Lexeme, []string{"STRING", "}"}, key.Position))
+			return entries
+		}
+
+		if token, ok := p.match(lexer.COLON); !ok {
+			p.addError(newParseError(token.Lexeme, []string{":"}, token.Position))
+		}
+
+		entries = append(entries, &MapEntry{
+			Key:      key.Lexeme,
+			Value:    p.ParseStringOrInteger(),
+			Position: key.Position,
+		})
+
+		switch p.lookahead.TokenType {
+		case lexer.RBRACE:
+			// Handled by the loop condition
+
+		case lexer.COMMA:
+			p.match(lexer.COMMA)
+
+		default:
+			p.addError(newParseError(p.lookahead.Lexeme, []string{",", "}"},
+				p.lookahead.Position))
+			return entries
+		}
+	}
+
+	// }
+	p.match(lexer.RBRACE)
+
+	return entries
+}
+
+// ParseContainer parses a container, e.g container(cpu: 1, memory: "1Gi").
+func (p *Parser) ParseContainer() Expression {
+	// container
+	containerToken, ok := p.match(lexer.CONTAINER)
+	if !ok {
+		p.addError(newParseError(containerToken.Lexeme, []string{"container"}, containerToken.Position))
+	}
+
+	// (
+	if token, ok := p.match(lexer.LPAREN); !ok {
+		p.addError(newParseError(token.Lexeme, []string{"("}, token.Position))
+	}
+
+	container := &ContainerExpression{Position: containerToken.Position}
+
+	for p.lookahead.TokenType != lexer.RPAREN {
+		field, ok := p.match(lexer.CPU, lexer.MEMORY, lexer.GPU)
+		if !ok {
+			p.addError(newParseError(field.Lexeme, []string{"cpu", "memory", "gpu", ")"}, field.Position))
+			return container
+		}
+
+		if token, ok := p.match(lexer.COLON); !ok {
+			p.addError(newParseError(token.Lexeme, []string{":"}, token.Position))
+		}
+
+		switch field.TokenType {
+		case lexer.CPU:
+			container.CPU = p.ParseStringOrInteger()
+		case lexer.MEMORY:
+			container.Memory = p.ParseStringOrInteger()
+		case lexer.GPU:
+			container.GPU = p.ParseStringOrInteger()
+		}
+
+		switch p.lookahead.TokenType {
+		case lexer.RPAREN:
+			// Handled by the loop condition
+
+		case lexer.COMMA:
+			p.match(lexer.COMMA)
+
+		default:
+			p.addError(newParseError(p.lookahead.Lexeme, []string{",", ")"},
+				p.lookahead.Position))
+			return container
+		}
+	}
+
+	// )
+	p.match(lexer.RPAREN)
+
+	return container
+}
+
 func (p *Parser) ParseStringOrInteger() Expression {
 	switch p.lookahead.TokenType {
 	case lexer.STRING:
@@ -206,8 +661,27 @@ func (p *Parser) ParseStringOrInteger() Expression {
 	case lexer.INTEGER:
 		return p.ParseInteger()
 
+	case lexer.IDENT, lexer.CPU, lexer.MEMORY, lexer.GPU:
+		// e.g pod(cpu: cpu) inside a template
+		return p.ParseIdentifier()
+
+	default:
+		p.addError(newParseError(p.lookahead.Lexeme, []string{"STRING", "INTEGER", "IDENT"},
+			p.lookahead.Position))
+		return nil
+	}
+}
+
+func (p *Parser) ParseIntegerOrIdentifier() Expression {
+	switch p.lookahead.TokenType {
+	case lexer.INTEGER:
+		return p.ParseInteger()
+
+	case lexer.IDENT, lexer.CPU, lexer.MEMORY, lexer.GPU:
+		return p.ParseIdentifier()
+
 	default:
-		p.addError(newParseError(p.lookahead.Lexeme, []string{"STRING", "INTEGER"},
+		p.addError(newParseError(p.lookahead.Lexeme, []string{"INTEGER", "IDENT"},
 			p.lookahead.Position))
 		return nil
 	}
@@ -471,6 +471,222 @@ func TestComplexExpression(t *testing.T) {
 	}, expression)
 }
 
+func TestLetStatement(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(`
+		let api = pod(cpu: 1)
+		let worker = pod(memory: "1Gi") * 2
+		api * 3 + worker
+	`))
+
+	program := p.ParseProgram()
+
+	assert.Empty(t, p.Errors)
+	assert.EqualValues(t, &parser.Program{
+		Statements: []parser.Statement{
+			&parser.LetStatement{
+				Name:  "api",
+				Value: &parser.PodExpression{CPU: &parser.IntLiteral{Value: 1}},
+			},
+			&parser.LetStatement{
+				Name: "worker",
+				Value: &parser.ArithmeticExpression{
+					Operator: parser.Multiply,
+					LHS:      &parser.PodExpression{Memory: &parser.StringLiteral{Value: "1Gi"}},
+					RHS:      &parser.IntLiteral{Value: 2},
+				},
+			},
+		},
+		Expression: &parser.ArithmeticExpression{
+			Operator: parser.Add,
+			LHS: &parser.ArithmeticExpression{
+				Operator: parser.Multiply,
+				LHS:      &parser.Identifier{Name: "api"},
+				RHS:      &parser.IntLiteral{Value: 3},
+			},
+			RHS: &parser.Identifier{Name: "worker"},
+		},
+	}, program)
+}
+
+func TestLetStatementWithoutAssign(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader("let api pod() api"))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
+func TestLetStatementWithoutName(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader("let = pod() api"))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
+func TestLetStatementAfterExpression(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader("pod() let api = pod()"))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
+func TestParsePosition(t *testing.T) {
+	_, errors := parser.Parse("let api = pod()\n  api api")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, lexer.Position{Line: 1, Column: 6}, errors[0].Pos)
+}
+
+func TestDefStatement(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(`def svc(cpu, mem, replicas) = pod(cpu: cpu, memory: mem) * replicas
+		svc("250m", "1Gi", 3)`))
+	program := p.ParseProgram()
+
+	assert.Empty(t, p.Errors)
+	assert.Equal(t, &parser.Program{
+		Statements: []parser.Statement{
+			&parser.DefStatement{
+				Name: "svc",
+				Parameters: []*parser.Identifier{
+					{Name: "cpu"},
+					{Name: "mem"},
+					{Name: "replicas"},
+				},
+				Body: &parser.ArithmeticExpression{
+					Operator: parser.Multiply,
+					LHS: &parser.PodExpression{
+						CPU:    &parser.Identifier{Name: "cpu"},
+						Memory: &parser.Identifier{Name: "mem"},
+					},
+					RHS: &parser.Identifier{Name: "replicas"},
+				},
+			},
+		},
+		Expression: &parser.CallExpression{
+			Name: "svc",
+			Arguments: []parser.Expression{
+				&parser.StringLiteral{Value: "250m"},
+				&parser.StringLiteral{Value: "1Gi"},
+				&parser.IntLiteral{Value: 3},
+			},
+		},
+	}, program)
+}
+
+func TestDefStatementWithoutParameters(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader("def svc() = pod() svc()"))
+	program := p.ParseProgram()
+
+	assert.Empty(t, p.Errors)
+	assert.Equal(t, &parser.CallExpression{Name: "svc", Arguments: []parser.Expression{}}, program.Expression)
+}
+
+func TestDefStatementWithoutAssign(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader("def svc(cpu) pod(cpu: cpu) svc(1)"))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
+func TestCallExpressionUnterminated(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader("def svc(cpu) = pod(cpu: cpu) svc(1"))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
+func TestParsePodContainers(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(
+		`pod(containers: [container(cpu: 1, memory: "1Gi"), sidecar], init: [container(cpu: 2)])`))
+	program := p.ParseProgram()
+
+	assert.Empty(t, p.Errors)
+	assert.Equal(t, &parser.PodExpression{
+		Containers: []parser.Expression{
+			&parser.ContainerExpression{
+				CPU:    &parser.IntLiteral{Value: 1},
+				Memory: &parser.StringLiteral{Value: "1Gi"},
+			},
+			&parser.Identifier{Name: "sidecar"},
+		},
+		InitContainers: []parser.Expression{
+			&parser.ContainerExpression{CPU: &parser.IntLiteral{Value: 2}},
+		},
+	}, program.Expression)
+}
+
+func TestParseContainerLet(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(`let sidecar = container(cpu: "100m") pod(containers: [])`))
+	program := p.ParseProgram()
+
+	assert.Empty(t, p.Errors)
+	assert.Equal(t, &parser.ContainerExpression{CPU: &parser.StringLiteral{Value: "100m"}},
+		program.Statements[0].(*parser.LetStatement).Value)
+	assert.Equal(t, &parser.PodExpression{Containers: []parser.Expression{}}, program.Expression)
+}
+
+func TestParseContainerListUnterminated(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(`pod(containers: [container(cpu: 1))`))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
+func TestParseContainerInvalidField(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(`pod(containers: [container(replicas: 1)])`))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
+func TestParsePodScheduling(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(
+		`pod(gpu: 1, nodeSelector: {"gpu": "true", "zone": zone}, tolerations: ["nvidia.com/gpu:NoSchedule", dedicated])`))
+	program := p.ParseProgram()
+
+	assert.Empty(t, p.Errors)
+	assert.Equal(t, &parser.PodExpression{
+		GPU: &parser.IntLiteral{Value: 1},
+		NodeSelector: []*parser.MapEntry{
+			{Key: "gpu", Value: &parser.StringLiteral{Value: "true"}},
+			{Key: "zone", Value: &parser.Identifier{Name: "zone"}},
+		},
+		Tolerations: []parser.Expression{
+			&parser.StringLiteral{Value: "nvidia.com/gpu:NoSchedule"},
+			&parser.Identifier{Name: "dedicated"},
+		},
+	}, program.Expression)
+}
+
+func TestParseNodeSelectorWithoutStringKey(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(`pod(nodeSelector: {gpu: "true"})`))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
+func TestParseSpread(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(`spread(api * 3, by: "zone", maxSkew: 2)`))
+	program := p.ParseProgram()
+
+	assert.Empty(t, p.Errors)
+	assert.Equal(t, &parser.SpreadExpression{
+		Expression: &parser.ArithmeticExpression{
+			Operator: parser.Multiply,
+			LHS:      &parser.Identifier{Name: "api"},
+			RHS:      &parser.IntLiteral{Value: 3},
+		},
+		By:      &parser.StringLiteral{Value: "zone"},
+		MaxSkew: &parser.IntLiteral{Value: 2},
+	}, program.Expression)
+}
+
+func TestParseSpreadInvalidField(t *testing.T) {
+	p := newParserNoPositions(strings.NewReader(`spread(pod() * 3, replicas: 3)`))
+	p.ParseProgram()
+
+	assert.NotEmpty(t, p.Errors)
+}
+
 func newParserNoPositions(reader io.Reader) *parser.Parser {
 	scanner := &lexer.Scanner{
 		Reader:           bufio.NewReader(reader),
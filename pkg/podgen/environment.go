@@ -0,0 +1,54 @@
+package podgen
+
+import (
+	"github.com/aporia-ai/kubesurvival/v2/pkg/lexer"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/parser"
+)
+
+// environment is a linked list of name bindings, where the most recent binding comes first.
+// A nil environment is empty.
+type environment struct {
+	name     string
+	position lexer.Position
+	parent   *environment
+
+	// A name is bound either to an expression (let statements and template arguments) or to
+	// a template. Both are evaluated in scope, so they can only refer to names that were
+	// visible where they were defined.
+	value    parser.Expression
+	template *parser.DefStatement
+	scope    *environment
+}
+
+// bind returns a new environment with the name bound to a value that is evaluated in scope.
+func (e *environment) bind(name string, value parser.Expression, scope *environment, position lexer.Position) *environment {
+	return &environment{
+		name:     name,
+		position: position,
+		parent:   e,
+		value:    value,
+		scope:    scope,
+	}
+}
+
+// bindTemplate returns a new environment with the template bound to its name.
+func (e *environment) bindTemplate(template *parser.DefStatement) *environment {
+	return &environment{
+		name:     template.Name,
+		position: template.Position,
+		parent:   e,
+		template: template,
+		scope:    e,
+	}
+}
+
+// lookup returns the most recent binding of the name, or nil if it's not bound.
+func (e *environment) lookup(name string) *environment {
+	for binding := e; binding != nil; binding = binding.parent {
+		if binding.name == name {
+			return binding
+		}
+	}
+
+	return nil
+}
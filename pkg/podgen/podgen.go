@@ -3,7 +3,10 @@ package podgen
 import (
 	"fmt"
 
+	"github.com/aporia-ai/kubesurvival/v2/pkg/admission"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/lexer"
 	"github.com/aporia-ai/kubesurvival/v2/pkg/parser"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/topologyspread"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,20 +18,155 @@ type PodGenerator struct {
 	errors          []Error
 	pods            []*corev1.Pod
 	currentPodIndex int64
+	env             *environment
+
+	// Pods of every spread expression share a label with its index
+	currentSpreadIndex int64
 }
 
-// Podgen generates a list of pods from an expression
-func Podgen(expression parser.Expression) ([]*corev1.Pod, []Error) {
+// Podgen generates a list of pods from a program
+func Podgen(program *parser.Program) ([]*corev1.Pod, []Error) {
 	c := &PodGenerator{
 		errors: []Error{},
 		pods:   []*corev1.Pod{},
 	}
 
-	c.PodgenExpression(expression)
+	for _, statement := range program.Statements {
+		c.PodgenStatement(statement)
+	}
+
+	c.PodgenExpression(program.Expression)
 
 	return c.pods, c.errors
 }
 
+// PodgenStatement processes a statement.
+func (c *PodGenerator) PodgenStatement(node parser.Statement) {
+	switch s := node.(type) {
+	case *parser.LetStatement:
+		c.PodgenLetStatement(s)
+	case *parser.DefStatement:
+		c.PodgenDefStatement(s)
+	}
+}
+
+// PodgenLetStatement binds a name to an expression. The expression is generated every time
+// the name is used, so each use creates new pods. Undefined names in the expression are
+// reported here even if the name is never used, and the name is then bound to nothing, so
+// uses of it don't report them again.
+func (c *PodGenerator) PodgenLetStatement(node *parser.LetStatement) {
+	if !c.checkNotDefined(node.Name, node.Position) {
+		return
+	}
+
+	value := node.Value
+	if !c.checkNamesDefined(c.env, node.Value) {
+		value = nil
+	}
+
+	c.env = c.env.bind(node.Name, value, c.env, node.Position)
+}
+
+// PodgenDefStatement binds a name to a template.
+func (c *PodGenerator) PodgenDefStatement(node *parser.DefStatement) {
+	if !c.checkNotDefined(node.Name, node.Position) {
+		return
+	}
+
+	// Parameter names must be unique. The template is bound anyway, so calls to it
+	// don't report it as undefined.
+	parameters := map[string]bool{}
+	scope := c.env
+	for _, parameter := range node.Parameters {
+		if parameters[parameter.Name] {
+			c.errors = append(c.errors, Error{
+				Message: fmt.Sprintf("duplicate parameter %s in template %s", parameter.Name, node.Name),
+				Pos:     parameter.Position,
+			})
+		}
+
+		parameters[parameter.Name] = true
+		scope = scope.bind(parameter.Name, nil, nil, parameter.Position)
+	}
+
+	// Like let statements, templates with undefined names are reported once and generate nothing
+	if !c.checkNamesDefined(scope, node.Body) {
+		invalid := *node
+		invalid.Body = nil
+		node = &invalid
+	}
+
+	c.env = c.env.bindTemplate(node)
+}
+
+// checkNotDefined adds an error if the name is already defined.
+func (c *PodGenerator) checkNotDefined(name string, position lexer.Position) bool {
+	if previous := c.env.lookup(name); previous != nil {
+		c.errors = append(c.errors, Error{
+			Message: fmt.Sprintf("%s is already defined (previously on line %d, char %d)",
+				name, previous.position.Line+1, previous.position.Column+1),
+			Pos: position,
+		})
+		return false
+	}
+
+	return true
+}
+
+// checkNamesDefined adds an error for every name in an expression that isn't defined in env,
+// and returns false if there are any.
+func (c *PodGenerator) checkNamesDefined(env *environment, node parser.Expression) bool {
+	ok := true
+	check := func(children ...parser.Expression) {
+		for _, child := range children {
+			if !c.checkNamesDefined(env, child) {
+				ok = false
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *parser.Identifier:
+		if env.lookup(n.Name) == nil {
+			c.errors = append(c.errors, Error{
+				Message: fmt.Sprintf("undefined: %s", n.Name),
+				Pos:     n.Position,
+			})
+			ok = false
+		}
+
+	case *parser.CallExpression:
+		if env.lookup(n.Name) == nil {
+			c.errors = append(c.errors, Error{
+				Message: fmt.Sprintf("undefined: %s", n.Name),
+				Pos:     n.Position,
+			})
+			ok = false
+		}
+		check(n.Arguments...)
+
+	case *parser.ArithmeticExpression:
+		check(n.LHS, n.RHS)
+
+	case *parser.PodExpression:
+		check(n.CPU, n.Memory, n.GPU)
+		check(n.Containers...)
+		check(n.InitContainers...)
+		for _, entry := range n.NodeSelector {
+			check(entry.Value)
+		}
+		check(n.Tolerations...)
+
+	case *parser.ContainerExpression:
+		check(n.CPU, n.Memory, n.GPU)
+
+	case *parser.SpreadExpression:
+		check(n.Expression, n.By, n.MaxSkew)
+	}
+
+	return ok
+}
+
 // PodgenExpression generates pods for an expression.
 func (c *PodGenerator) PodgenExpression(node parser.Expression) {
 	switch s := node.(type) {
@@ -36,27 +174,255 @@ func (c *PodGenerator) PodgenExpression(node parser.Expression) {
 		c.PodgenPodExpression(s)
 	case *parser.ArithmeticExpression:
 		c.PodgenArithmeticExpression(s)
+	case *parser.Identifier:
+		c.PodgenIdentifier(s)
+	case *parser.CallExpression:
+		c.PodgenCallExpression(s)
+	case *parser.SpreadExpression:
+		c.PodgenSpreadExpression(s)
+	case *parser.IntLiteral, *parser.StringLiteral:
+		c.errors = append(c.errors, Error{
+			Message: "expected pods, found a literal",
+			Pos:     position(node),
+		})
+	case *parser.ContainerExpression:
+		c.errors = append(c.errors, Error{
+			Message: "expected pods, found a container",
+			Pos:     position(node),
+		})
+	}
+}
+
+// PodgenIdentifier generates pods for the expression bound to an identifier.
+func (c *PodGenerator) PodgenIdentifier(node *parser.Identifier) {
+	value, env := c.resolve(node)
+	if value == nil {
+		return
+	}
+
+	c.podgenInEnvironment(env, value)
+}
+
+// PodgenCallExpression generates pods for a template call.
+func (c *PodGenerator) PodgenCallExpression(node *parser.CallExpression) {
+	binding := c.env.lookup(node.Name)
+	if binding == nil {
+		c.errors = append(c.errors, Error{
+			Message: fmt.Sprintf("undefined: %s", node.Name),
+			Pos:     node.Position,
+		})
+		return
+	}
+
+	template := binding.template
+	if template == nil {
+		c.errors = append(c.errors, Error{
+			Message: fmt.Sprintf("%s is not a template", node.Name),
+			Pos:     node.Position,
+		})
+		return
+	}
+
+	if len(node.Arguments) != len(template.Parameters) {
+		c.errors = append(c.errors, Error{
+			Message: fmt.Sprintf("%s expects %d arguments, got %d",
+				node.Name, len(template.Parameters), len(node.Arguments)),
+			Pos: node.Position,
+		})
+		return
+	}
+
+	// Arguments are evaluated where the template is called, while the template body is
+	// evaluated where the template is defined.
+	env := binding.scope
+	for i, parameter := range template.Parameters {
+		env = env.bind(parameter.Name, node.Arguments[i], c.env, parameter.Position)
+	}
+
+	// Errors in the template body are reported where it's called, with their position in the body
+	errorCount := len(c.errors)
+	c.podgenInEnvironment(env, template.Body)
+	for i := errorCount; i < len(c.errors); i++ {
+		c.errors[i] = Error{
+			Message: fmt.Sprintf("%s (line %d, char %d of %s)",
+				c.errors[i].Message, c.errors[i].Pos.Line+1, c.errors[i].Pos.Column+1, node.Name),
+			Pos: node.Position,
+		}
+	}
+}
+
+// resolve follows identifiers until it finds a non-identifier expression. Returns that
+// expression and the environment in which it should be evaluated, or nil if a name
+// is undefined.
+func (c *PodGenerator) resolve(node parser.Expression) (parser.Expression, *environment) {
+	env := c.env
+	for {
+		identifier, ok := node.(*parser.Identifier)
+		if !ok {
+			return node, env
+		}
+
+		binding := env.lookup(identifier.Name)
+		if binding == nil {
+			c.errors = append(c.errors, Error{
+				Message: fmt.Sprintf("undefined: %s", identifier.Name),
+				Pos:     identifier.Position,
+			})
+			return nil, nil
+		}
+
+		if binding.template != nil {
+			c.errors = append(c.errors, Error{
+				Message: fmt.Sprintf("%s is a template and must be called with %d arguments",
+					identifier.Name, len(binding.template.Parameters)),
+				Pos: identifier.Position,
+			})
+			return nil, nil
+		}
+
+		node, env = binding.value, binding.scope
+	}
+}
+
+// podgenInEnvironment generates pods for an expression in the given environment.
+func (c *PodGenerator) podgenInEnvironment(env *environment, node parser.Expression) {
+	previous := c.env
+	c.env = env
+	c.PodgenExpression(node)
+	c.env = previous
+}
+
+// PodgenSpreadExpression generates pods that are spread across nodes or zones. Spreading across
+// nodes without maxSkew uses pod anti-affinity, so there's at most one of the pods on every node.
+// Otherwise, a topology spread constraint is used.
+func (c *PodGenerator) PodgenSpreadExpression(node *parser.SpreadExpression) {
+	if node.By == nil {
+		c.errors = append(c.errors, Error{
+			Message: "spread requires by, e.g spread(pod() * 3, by: \"zone\")",
+			Pos:     node.Position,
+		})
+		return
+	}
+
+	by, ok := c.parseString(node.By)
+	if !ok {
+		return
+	}
+
+	topologyKey := by
+	switch by {
+	case "node":
+		topologyKey = "kubernetes.io/hostname"
+	case "zone":
+		topologyKey = "topology.kubernetes.io/zone"
+	}
+
+	maxSkew := int64(0)
+	if node.MaxSkew != nil {
+		value, _ := c.resolve(node.MaxSkew)
+		if value == nil {
+			return
+		}
+
+		literal, ok := value.(*parser.IntLiteral)
+		if !ok || literal.Value < 1 {
+			c.errors = append(c.errors, Error{
+				Message: "maxSkew must be a positive integer",
+				Pos:     position(node.MaxSkew),
+			})
+			return
+		}
+
+		maxSkew = literal.Value
+	}
+
+	first := len(c.pods)
+	c.PodgenExpression(node.Expression)
+
+	label := fmt.Sprintf("kubesurvival.aporia.com/spread-%d", c.currentSpreadIndex)
+	c.currentSpreadIndex++
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{label: "true"}}
+
+	for _, pod := range c.pods[first:] {
+		if pod.Labels == nil {
+			pod.Labels = map[string]string{}
+		}
+		pod.Labels[label] = "true"
+
+		if by == "node" && maxSkew == 0 {
+			if pod.Spec.Affinity == nil {
+				pod.Spec.Affinity = &corev1.Affinity{}
+			}
+			if pod.Spec.Affinity.PodAntiAffinity == nil {
+				pod.Spec.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+			}
+
+			antiAffinity := pod.Spec.Affinity.PodAntiAffinity
+			antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+				antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+				corev1.PodAffinityTerm{LabelSelector: selector, TopologyKey: topologyKey},
+			)
+			continue
+		}
+
+		if maxSkew == 0 {
+			maxSkew = 1
+		}
+
+		err := topologyspread.Add(pod, topologyspread.Constraint{
+			MaxSkew:           int32(maxSkew),
+			TopologyKey:       topologyKey,
+			WhenUnsatisfiable: "DoNotSchedule",
+			LabelSelector:     selector,
+		})
+		if err != nil {
+			c.errors = append(c.errors, Error{
+				Message: err.Error(),
+				Pos:     node.Position,
+			})
+			return
+		}
 	}
 }
 
 // PodgenPodExpression generates pods for a pod expression.
 func (c *PodGenerator) PodgenPodExpression(node *parser.PodExpression) {
-	resources := corev1.ResourceList{}
+	containers := []corev1.Container{}
+
+	if node.CPU != nil || node.Memory != nil || node.GPU != nil || len(node.Containers) == 0 {
+		if len(node.Containers) > 0 {
+			c.errors = append(c.errors, Error{
+				Message: "a pod can't have both containers and cpu, memory or gpu",
+				Pos:     node.Position,
+			})
+			return
+		}
+
+		containers = append(containers, corev1.Container{
+			Name:  "container",
+			Image: "container",
+			Resources: corev1.ResourceRequirements{
+				Requests: c.podgenResources(node.CPU, node.Memory, node.GPU),
+			},
+		})
+	}
 
-	cpu := c.ParseQuantity(node.CPU)
-	if cpu != nil {
-		resources["cpu"] = *cpu
+	for i, container := range node.Containers {
+		containers = append(containers, c.PodgenContainer(container, fmt.Sprintf("container-%d", i)))
 	}
 
-	memory := c.ParseQuantity(node.Memory)
-	if memory != nil {
-		resources["memory"] = *memory
+	initContainers := []corev1.Container{}
+	for i, container := range node.InitContainers {
+		initContainers = append(initContainers, c.PodgenContainer(container, fmt.Sprintf("init-%d", i)))
 	}
 
-	gpu := c.ParseQuantity(node.GPU)
-	if gpu != nil {
-		resources["nvidia.com/gpu"] = *gpu
+	spec := v1.PodSpec{
+		Containers:     containers,
+		InitContainers: initContainers,
+		NodeSelector:   c.podgenNodeSelector(node.NodeSelector),
+		Tolerations:    c.podgenTolerations(node.Tolerations),
 	}
+	admission.AddExtendedResourceTolerations(&spec)
 
 	c.pods = append(c.pods, &corev1.Pod{
 		TypeMeta: metav1.TypeMeta{
@@ -66,24 +432,141 @@ func (c *PodGenerator) PodgenPodExpression(node *parser.PodExpression) {
 		ObjectMeta: metav1.ObjectMeta{
 			Name: fmt.Sprintf("pod-%d", c.currentPodIndex),
 		},
-		Spec: v1.PodSpec{
-			Containers: []v1.Container{
-				{
-					Name:  "container",
-					Image: "container",
-					Resources: corev1.ResourceRequirements{
-						Requests: resources,
-					},
-				},
-			},
-		},
+		Spec: spec,
 	})
 
 	c.currentPodIndex++
 }
 
+// PodgenContainer generates a container for a container expression, or an identifier bound to one.
+func (c *PodGenerator) PodgenContainer(node parser.Expression, name string) corev1.Container {
+	result := corev1.Container{
+		Name:  name,
+		Image: name,
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{},
+		},
+	}
+
+	value, env := c.resolve(node)
+	if value == nil {
+		return result
+	}
+
+	container, ok := value.(*parser.ContainerExpression)
+	if !ok {
+		c.errors = append(c.errors, Error{
+			Message: "expected a container",
+			Pos:     position(node),
+		})
+		return result
+	}
+
+	// Container fields may refer to names where the container is defined
+	previous := c.env
+	c.env = env
+	result.Resources.Requests = c.podgenResources(container.CPU, container.Memory, container.GPU)
+	c.env = previous
+
+	return result
+}
+
+// podgenNodeSelector generates the node selector of a pod.
+func (c *PodGenerator) podgenNodeSelector(entries []*parser.MapEntry) map[string]string {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	nodeSelector := map[string]string{}
+	for _, entry := range entries {
+		if value, ok := c.parseString(entry.Value); ok {
+			nodeSelector[entry.Key] = value
+		}
+	}
+
+	return nodeSelector
+}
+
+// podgenTolerations generates the tolerations of a pod.
+func (c *PodGenerator) podgenTolerations(nodes []parser.Expression) []corev1.Toleration {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	tolerations := []corev1.Toleration{}
+	for _, node := range nodes {
+		value, ok := c.parseString(node)
+		if !ok {
+			continue
+		}
+
+		toleration, err := parseToleration(value)
+		if err != nil {
+			c.errors = append(c.errors, Error{
+				Message: err.Error(),
+				Pos:     position(node),
+			})
+			continue
+		}
+
+		tolerations = append(tolerations, toleration)
+	}
+
+	return tolerations
+}
+
+// parseString resolves an expression to a string. Integers are converted to strings.
+func (c *PodGenerator) parseString(node parser.Expression) (string, bool) {
+	value, _ := c.resolve(node)
+	switch s := value.(type) {
+	case nil:
+		return "", false
+
+	case *parser.StringLiteral:
+		return s.Value, true
+
+	case *parser.IntLiteral:
+		return fmt.Sprintf("%d", s.Value), true
+
+	default:
+		c.errors = append(c.errors, Error{
+			Message: "expected a string",
+			Pos:     position(node),
+		})
+
+		return "", false
+	}
+}
+
+// podgenResources generates the resource requests of a container.
+func (c *PodGenerator) podgenResources(cpu, memory, gpu parser.Expression) corev1.ResourceList {
+	resources := corev1.ResourceList{}
+
+	if quantity := c.ParseQuantity(cpu); quantity != nil {
+		resources["cpu"] = *quantity
+	}
+
+	if quantity := c.ParseQuantity(memory); quantity != nil {
+		resources["memory"] = *quantity
+	}
+
+	if quantity := c.ParseQuantity(gpu); quantity != nil {
+		resources["nvidia.com/gpu"] = *quantity
+	}
+
+	return resources
+}
+
 func (c *PodGenerator) ParseQuantity(node parser.Expression) *resource.Quantity {
-	switch q := node.(type) {
+	if node == nil {
+		return nil
+	}
+
+	value, _ := c.resolve(node)
+	switch q := value.(type) {
+	case nil:
+		return nil
+
 	case *parser.IntLiteral:
 		return resource.NewScaledQuantity(q.Value, 0)
 
@@ -92,7 +575,7 @@ func (c *PodGenerator) ParseQuantity(node parser.Expression) *resource.Quantity
 		if err != nil {
 			c.errors = append(c.errors, Error{
 				Message: err.Error(),
-				Pos:     q.Position,
+				Pos:     position(node),
 			})
 
 			return nil
@@ -101,6 +584,11 @@ func (c *PodGenerator) ParseQuantity(node parser.Expression) *resource.Quantity
 		return &result
 
 	default:
+		c.errors = append(c.errors, Error{
+			Message: "expected a string or an integer",
+			Pos:     position(node),
+		})
+
 		return nil
 	}
 }
@@ -114,32 +602,63 @@ func (c *PodGenerator) PodgenArithmeticExpression(node *parser.ArithmeticExpress
 		c.PodgenExpression(node.RHS)
 
 	case parser.Multiply:
+		lhs, lhsEnv := c.resolve(node.LHS)
+		rhs, rhsEnv := c.resolve(node.RHS)
+		if lhs == nil || rhs == nil {
+			return
+		}
+
 		// One of LHS or RHS must be an integer.
 
 		// Try to parse LHS as integer.
-		multiplier, isLHSInteger := node.LHS.(*parser.IntLiteral)
+		exp, env := rhs, rhsEnv
+		multiplier, isLHSInteger := lhs.(*parser.IntLiteral)
 		if !isLHSInteger {
 			// If it didn't work, then RHS must be an integer.
 			var ok bool
-			if multiplier, ok = node.RHS.(*parser.IntLiteral); !ok {
+			if multiplier, ok = rhs.(*parser.IntLiteral); !ok {
 				c.errors = append(c.errors, Error{
 					Message: "one of [lhs, rhs] must be an integer in a multiply expression",
 					Pos:     node.Position,
 				})
 				return
 			}
+
+			exp, env = lhs, lhsEnv
 		}
 
 		var i int64
 		for i = 0; i < multiplier.Value; i++ {
-			var exp parser.Expression
-			if isLHSInteger {
-				exp = node.RHS
-			} else {
-				exp = node.LHS
+			// Every replica would report the same errors
+			errorCount := len(c.errors)
+			c.podgenInEnvironment(env, exp)
+			if len(c.errors) > errorCount {
+				return
 			}
-
-			c.PodgenExpression(exp)
 		}
 	}
 }
+
+// position returns the position of an expression in the source code.
+func position(node parser.Expression) lexer.Position {
+	switch n := node.(type) {
+	case *parser.Identifier:
+		return n.Position
+	case *parser.IntLiteral:
+		return n.Position
+	case *parser.StringLiteral:
+		return n.Position
+	case *parser.ArithmeticExpression:
+		return n.Position
+	case *parser.CallExpression:
+		return n.Position
+	case *parser.PodExpression:
+		return n.Position
+	case *parser.ContainerExpression:
+		return n.Position
+	case *parser.SpreadExpression:
+		return n.Position
+	default:
+		return lexer.Position{}
+	}
+}
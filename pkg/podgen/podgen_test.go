@@ -0,0 +1,332 @@
+package podgen_test
+
+import (
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/lexer"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/parser"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/podgen"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/topologyspread"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodgenLet(t *testing.T) {
+	pods, errors := podgenString(t, `
+		let api = pod(cpu: "500m", memory: "1Gi")
+		let worker = pod(cpu: 2) * 2
+		api * 3 + worker
+	`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 5)
+	assert.Equal(t, "500m", pods[0].Spec.Containers[0].Resources.Requests.Cpu().String())
+	assert.Equal(t, "2", pods[4].Spec.Containers[0].Resources.Requests.Cpu().String())
+
+	// Every use of a name creates new pods
+	assert.Equal(t, "pod-0", pods[0].Name)
+	assert.Equal(t, "pod-4", pods[4].Name)
+}
+
+func TestPodgenLetReferencesPreviousLet(t *testing.T) {
+	pods, errors := podgenString(t, `
+		let api = pod(cpu: 1)
+		let env = api * 2 + pod(cpu: 2)
+		env * 3
+	`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 9)
+}
+
+func TestPodgenUndefinedName(t *testing.T) {
+	_, errors := podgenString(t, "let api = pod(cpu: 1)\napi + worker")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "undefined: worker at line 2, char 7", errors[0].Error())
+	assert.Equal(t, lexer.Position{Line: 1, Column: 6}, errors[0].Pos)
+}
+
+func TestPodgenLetCannotReferenceLaterLet(t *testing.T) {
+	_, errors := podgenString(t, `
+		let api = worker
+		let worker = pod(cpu: 1)
+		api
+	`)
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "undefined: worker at line 2, char 13", errors[0].Error())
+}
+
+func TestPodgenUndefinedNameInUnusedLet(t *testing.T) {
+	_, errors := podgenString(t, "let api = pod(cpu: size)\nlet worker = spread(api * 2, by: zone)\npod(cpu: 1)")
+
+	assert.Len(t, errors, 2)
+	assert.Equal(t, "undefined: size at line 1, char 20", errors[0].Error())
+	assert.Equal(t, "undefined: zone at line 2, char 34", errors[1].Error())
+}
+
+func TestPodgenUndefinedNameInUsedLet(t *testing.T) {
+	// The undefined name is reported where it's used in the let statement, and only once
+	_, errors := podgenString(t, "let api = pod(cpu: size)\napi * 2 + api")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "undefined: size at line 1, char 20", errors[0].Error())
+}
+
+func TestPodgenDuplicateName(t *testing.T) {
+	_, errors := podgenString(t, "let api = pod(cpu: 1)\nlet api = pod(cpu: 2)\napi")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "api is already defined (previously on line 1, char 1) at line 2, char 1", errors[0].Error())
+}
+
+func TestPodgenTemplate(t *testing.T) {
+	pods, errors := podgenString(t, `
+		def svc(cpu, mem, replicas) = pod(cpu: cpu, memory: mem) * replicas
+		svc("250m", "1Gi", 3) + svc(2, "4Gi", 1)
+	`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 4)
+	assert.Equal(t, "250m", pods[0].Spec.Containers[0].Resources.Requests.Cpu().String())
+	assert.Equal(t, "1Gi", pods[2].Spec.Containers[0].Resources.Requests.Memory().String())
+	assert.Equal(t, "2", pods[3].Spec.Containers[0].Resources.Requests.Cpu().String())
+}
+
+func TestPodgenTemplateCallsTemplate(t *testing.T) {
+	pods, errors := podgenString(t, `
+		def svc(cpu, replicas) = pod(cpu: cpu) * replicas
+		def env(cpu) = svc(cpu, 2) + svc("100m", 2)
+		env(1) * 2
+	`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 8)
+	assert.Equal(t, "1", pods[0].Spec.Containers[0].Resources.Requests.Cpu().String())
+	assert.Equal(t, "100m", pods[2].Spec.Containers[0].Resources.Requests.Cpu().String())
+}
+
+func TestPodgenTemplateArity(t *testing.T) {
+	_, errors := podgenString(t, "def svc(cpu, mem) = pod(cpu: cpu, memory: mem)\nsvc(\"1\")")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "svc expects 2 arguments, got 1 at line 2, char 1", errors[0].Error())
+}
+
+func TestPodgenTemplateDuplicateParameter(t *testing.T) {
+	_, errors := podgenString(t, "def svc(cpu, cpu) = pod(cpu: cpu)\nsvc(1, 2)")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "duplicate parameter cpu in template svc at line 1, char 14", errors[0].Error())
+}
+
+func TestPodgenTemplateWithoutCall(t *testing.T) {
+	_, errors := podgenString(t, "def svc(cpu) = pod(cpu: cpu)\nsvc * 2")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "svc is a template and must be called with 1 arguments at line 2, char 1", errors[0].Error())
+}
+
+func TestPodgenCallNotTemplate(t *testing.T) {
+	_, errors := podgenString(t, "let api = pod()\napi(1)")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "api is not a template at line 2, char 1", errors[0].Error())
+}
+
+func TestPodgenTemplateErrorAtCallSite(t *testing.T) {
+	_, errors := podgenString(t, "def f(x) = pod(cpu: x)\nf(f(1))")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "expected a string or an integer (line 1, char 21 of f) at line 2, char 1", errors[0].Error())
+	assert.Equal(t, lexer.Position{Line: 1, Column: 0}, errors[0].Pos)
+}
+
+func TestPodgenNestedTemplateErrorAtCallSite(t *testing.T) {
+	_, errors := podgenString(t, `def svc(cpu) = pod(cpu: cpu)
+def env(cpu) = svc(cpu) * 2
+pod(cpu: 1) + env("lots")`)
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "quantities must match the regular expression '^([+-]?[0-9.]+)([eEinumkKMGTP]*[-+]?[0-9]*)$' "+
+		"(line 1, char 25 of svc) (line 2, char 16 of env) at line 3, char 15", errors[0].Error())
+}
+
+func TestPodgenUndefinedNameInTemplate(t *testing.T) {
+	// Templates are checked where they're defined, even if they're never called
+	_, errors := podgenString(t, "def svc(cpu) = pod(cpu: cpu, memory: mem)\ndef unused() = svc(1) + worker\nsvc(1)")
+
+	assert.Len(t, errors, 2)
+	assert.Equal(t, "undefined: mem at line 1, char 38", errors[0].Error())
+	assert.Equal(t, "undefined: worker at line 2, char 25", errors[1].Error())
+}
+
+func TestPodgenTemplateParametersAreLocal(t *testing.T) {
+	_, errors := podgenString(t, "def svc(cpu) = pod(cpu: cpu)\nsvc(1) + pod(cpu: cpu)")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "undefined: cpu at line 2, char 19", errors[0].Error())
+}
+
+func TestPodgenContainers(t *testing.T) {
+	pods, errors := podgenString(t, `
+		let envoy = container(cpu: "100m", memory: "128Mi")
+		pod(containers: [container(cpu: 1, memory: "1Gi"), envoy], init: [container(cpu: 2)]) * 2
+	`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 2)
+
+	spec := pods[0].Spec
+	assert.Len(t, spec.Containers, 2)
+	assert.Equal(t, "container-0", spec.Containers[0].Name)
+	assert.Equal(t, "1Gi", spec.Containers[0].Resources.Requests.Memory().String())
+	assert.Equal(t, "100m", spec.Containers[1].Resources.Requests.Cpu().String())
+	assert.Len(t, spec.InitContainers, 1)
+	assert.Equal(t, "2", spec.InitContainers[0].Resources.Requests.Cpu().String())
+}
+
+func TestPodgenContainersInTemplate(t *testing.T) {
+	pods, errors := podgenString(t, `
+		def svc(cpu, sidecar) = pod(containers: [container(cpu: cpu), sidecar])
+		svc(2, container(cpu: "50m"))
+	`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 1)
+	assert.Equal(t, "2", pods[0].Spec.Containers[0].Resources.Requests.Cpu().String())
+	assert.Equal(t, "50m", pods[0].Spec.Containers[1].Resources.Requests.Cpu().String())
+}
+
+func TestPodgenContainersAndResources(t *testing.T) {
+	_, errors := podgenString(t, `pod(cpu: 1, containers: [container(cpu: 1)])`)
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "a pod can't have both containers and cpu, memory or gpu at line 1, char 1", errors[0].Error())
+}
+
+func TestPodgenContainerNotAContainer(t *testing.T) {
+	_, errors := podgenString(t, "let api = pod()\npod(containers: [api])")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "expected a container at line 2, char 18", errors[0].Error())
+}
+
+func TestPodgenContainerAsPod(t *testing.T) {
+	_, errors := podgenString(t, "let envoy = container(cpu: 1)\nenvoy * 2")
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "expected pods, found a container at line 1, char 13", errors[0].Error())
+}
+
+func TestPodgenScheduling(t *testing.T) {
+	pods, errors := podgenString(t, `
+		def gpuPod(gpus) = pod(gpu: gpus, nodeSelector: {"gpu": "true"}, tolerations: ["nvidia.com/gpu:NoSchedule", "team=ml"])
+		gpuPod(2)
+	`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 1)
+	assert.Equal(t, map[string]string{"gpu": "true"}, pods[0].Spec.NodeSelector)
+	assert.Equal(t, []corev1.Toleration{
+		{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+		{Key: "team", Operator: corev1.TolerationOpEqual, Value: "ml"},
+	}, pods[0].Spec.Tolerations)
+}
+
+func TestPodgenExtendedResourceTolerations(t *testing.T) {
+	pods, errors := podgenString(t, `pod(cpu: 2, gpu: 1) + pod(cpu: 2)`)
+
+	// Like on GKE, pods that request GPUs tolerate the taint of GPU nodes
+	assert.Empty(t, errors)
+	assert.Equal(t, []corev1.Toleration{
+		{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+	}, pods[0].Spec.Tolerations)
+	assert.Empty(t, pods[1].Spec.Tolerations)
+}
+
+func TestPodgenInvalidToleration(t *testing.T) {
+	tests := map[string]string{
+		`pod(tolerations: ["gpu:NoWay"])`:   "invalid toleration effect NoWay, expected NoSchedule, PreferNoSchedule or NoExecute at line 1, char 20",
+		`pod(tolerations: [":NoSchedule"])`: "invalid toleration :NoSchedule, key is empty at line 1, char 20",
+		`pod(tolerations: ["=gpu"])`:        "invalid toleration =gpu, key is empty at line 1, char 20",
+	}
+
+	for expression, expected := range tests {
+		_, errors := podgenString(t, expression)
+
+		assert.Len(t, errors, 1, expression)
+		assert.Equal(t, expected, errors[0].Error(), expression)
+	}
+}
+
+func TestPodgenSpreadByNode(t *testing.T) {
+	pods, errors := podgenString(t, `spread(pod(cpu: 1) * 2, by: "node") + pod(cpu: 1)`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 3)
+
+	for _, pod := range pods[:2] {
+		assert.Equal(t, map[string]string{"kubesurvival.aporia.com/spread-0": "true"}, pod.Labels)
+		assert.Equal(t, []corev1.PodAffinityTerm{{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"kubesurvival.aporia.com/spread-0": "true"},
+			},
+			TopologyKey: "kubernetes.io/hostname",
+		}}, pod.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+	}
+
+	assert.Empty(t, pods[2].Labels)
+	assert.Nil(t, pods[2].Spec.Affinity)
+}
+
+func TestPodgenSpreadByZone(t *testing.T) {
+	pods, errors := podgenString(t, `
+		def zonal(replicas, skew) = spread(pod(cpu: 1) * replicas, by: "zone", maxSkew: skew)
+		zonal(3, 2) + spread(pod(cpu: 1), by: "node", maxSkew: 1)
+	`)
+
+	assert.Empty(t, errors)
+	assert.Len(t, pods, 4)
+
+	constraints, err := topologyspread.Get(pods[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []topologyspread.Constraint{{
+		MaxSkew:           2,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: "DoNotSchedule",
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"kubesurvival.aporia.com/spread-0": "true"},
+		},
+	}}, constraints)
+	assert.Nil(t, pods[0].Spec.Affinity)
+
+	constraints, err = topologyspread.Get(pods[3])
+	assert.NoError(t, err)
+	assert.Len(t, constraints, 1)
+	assert.Equal(t, "kubernetes.io/hostname", constraints[0].TopologyKey)
+	assert.Equal(t, map[string]string{"kubesurvival.aporia.com/spread-1": "true"}, pods[3].Labels)
+}
+
+func TestPodgenSpreadWithoutBy(t *testing.T) {
+	_, errors := podgenString(t, `spread(pod() * 3)`)
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, `spread requires by, e.g spread(pod() * 3, by: "zone") at line 1, char 1`, errors[0].Error())
+}
+
+func TestPodgenSpreadInvalidMaxSkew(t *testing.T) {
+	_, errors := podgenString(t, `spread(pod() * 3, by: "zone", maxSkew: 0)`)
+
+	assert.Len(t, errors, 1)
+	assert.Equal(t, "maxSkew must be a positive integer at line 1, char 40", errors[0].Error())
+}
+
+func podgenString(t *testing.T, s string) ([]*corev1.Pod, []podgen.Error) {
+	program, parseErrors := parser.Parse(s)
+	assert.Empty(t, parseErrors)
+
+	return podgen.Podgen(program)
+}
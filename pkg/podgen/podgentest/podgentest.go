@@ -0,0 +1,22 @@
+// Package podgentest generates pods for tests of the packages that simulate them.
+package podgentest
+
+import (
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/parser"
+	"github.com/aporia-ai/kubesurvival/v2/pkg/podgen"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Pods generates pods from a pod expression, and fails the test if it's invalid.
+func Pods(t *testing.T, expression string) []*v1.Pod {
+	program, parseErrors := parser.Parse(expression)
+	assert.Empty(t, parseErrors)
+
+	pods, podgenErrors := podgen.Podgen(program)
+	assert.Empty(t, podgenErrors)
+
+	return pods
+}
@@ -0,0 +1,40 @@
+package podgen
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// parseToleration parses a toleration in the format of a taint, e.g nvidia.com/gpu=true:NoSchedule.
+// Without a value, any value of the key is tolerated. Without an effect, all effects are tolerated.
+func parseToleration(s string) (corev1.Toleration, error) {
+	toleration := corev1.Toleration{Operator: corev1.TolerationOpExists}
+
+	keyValue := s
+	if separator := strings.LastIndex(s, ":"); separator != -1 {
+		toleration.Effect = corev1.TaintEffect(s[separator+1:])
+		switch toleration.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return toleration, errors.Errorf("invalid toleration effect %s, expected NoSchedule, PreferNoSchedule or NoExecute",
+				toleration.Effect)
+		}
+
+		keyValue = s[:separator]
+	}
+
+	parts := strings.SplitN(keyValue, "=", 2)
+	toleration.Key = parts[0]
+	if len(parts) == 2 {
+		toleration.Operator = corev1.TolerationOpEqual
+		toleration.Value = parts[1]
+	}
+
+	if toleration.Key == "" {
+		return toleration, errors.Errorf("invalid toleration %s, key is empty", s)
+	}
+
+	return toleration, nil
+}
@@ -0,0 +1,60 @@
+package topologyspread
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Annotation holds the topology spread constraints of a pod as JSON. Topology spread constraints
+// aren't part of the Kubernetes API version used by the simulator, so they're stored in an annotation.
+const Annotation = "kubesurvival.aporia.com/topology-spread-constraints"
+
+// Constraint is a topology spread constraint, with the same fields as in the Kubernetes API.
+type Constraint struct {
+	MaxSkew           int32                 `json:"maxSkew"`
+	TopologyKey       string                `json:"topologyKey"`
+	WhenUnsatisfiable string                `json:"whenUnsatisfiable"`
+	LabelSelector     *metav1.LabelSelector `json:"labelSelector,omitempty"`
+}
+
+// Get returns the topology spread constraints of a pod.
+func Get(pod *v1.Pod) ([]Constraint, error) {
+	value, ok := pod.Annotations[Annotation]
+	if !ok {
+		return nil, nil
+	}
+
+	constraints := []Constraint{}
+	if err := json.Unmarshal([]byte(value), &constraints); err != nil {
+		return nil, errors.Wrapf(err, "invalid topology spread constraints of pod %s", pod.Name)
+	}
+
+	return constraints, nil
+}
+
+// Add adds topology spread constraints to a pod.
+func Add(pod *v1.Pod, constraints ...Constraint) error {
+	if len(constraints) == 0 {
+		return nil
+	}
+
+	current, err := Get(pod)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(append(current, constraints...))
+	if err != nil {
+		return errors.Wrap(err, "could not serialize topology spread constraints")
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[Annotation] = string(value)
+
+	return nil
+}
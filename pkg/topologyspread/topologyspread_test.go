@@ -0,0 +1,55 @@
+package topologyspread_test
+
+import (
+	"testing"
+
+	"github.com/aporia-ai/kubesurvival/v2/pkg/topologyspread"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAddAndGet(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-0"}}
+
+	constraints, err := topologyspread.Get(pod)
+	assert.Nil(t, err)
+	assert.Empty(t, constraints)
+
+	// Adding no constraints doesn't add the annotation
+	assert.Nil(t, topologyspread.Add(pod))
+	assert.Nil(t, pod.Annotations)
+
+	zone := topologyspread.Constraint{
+		MaxSkew:           1,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: "DoNotSchedule",
+		LabelSelector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+	}
+	node := topologyspread.Constraint{MaxSkew: 2, TopologyKey: "kubernetes.io/hostname", WhenUnsatisfiable: "ScheduleAnyway"}
+
+	assert.Nil(t, topologyspread.Add(pod, zone))
+	assert.Equal(t,
+		`[{"maxSkew":1,"topologyKey":"topology.kubernetes.io/zone","whenUnsatisfiable":"DoNotSchedule","labelSelector":{"matchLabels":{"app":"web"}}}]`,
+		pod.Annotations[topologyspread.Annotation])
+
+	// Constraints are added to the existing ones
+	assert.Nil(t, topologyspread.Add(pod, node))
+	constraints, err = topologyspread.Get(pod)
+	assert.Nil(t, err)
+	assert.Equal(t, []topologyspread.Constraint{zone, node}, constraints)
+}
+
+func TestGetInvalidAnnotation(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "pod-0",
+		Annotations: map[string]string{topologyspread.Annotation: `{"maxSkew": 1}`},
+	}}
+
+	_, err := topologyspread.Get(pod)
+	assert.EqualError(t, err, "invalid topology spread constraints of pod pod-0: "+
+		"json: cannot unmarshal object into Go value of type []topologyspread.Constraint")
+
+	err = topologyspread.Add(pod, topologyspread.Constraint{MaxSkew: 1, TopologyKey: "kubernetes.io/hostname"})
+	assert.Error(t, err)
+}